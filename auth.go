@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// principalKey is the context key holding the authenticated Principal
+const principalKey = "principal"
+
+// adminScope grants access to the admin routes
+const adminScope = "admin"
+
+// Principal is an authenticated caller
+type Principal struct {
+	Subject string
+	Scopes  []string
+}
+
+// APIKey is a bearer token granting scopes to a subject
+type APIKey struct {
+	Token string
+	Principal
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// currentPrincipal returns the authenticated caller, or nil for anonymous requests
+func currentPrincipal(c *fiber.Ctx) *Principal {
+	p, _ := c.Locals(principalKey).(*Principal)
+	return p
+}
+
+// authenticate resolves the bearer token of the request, if any, to a Principal.
+// Requests without a token continue anonymously; unknown tokens are rejected.
+func authenticate(cfg Config) fiber.Handler {
+	keys := cfg.APIKeys
+	if cfg.AdminToken != "" {
+		keys = append(keys, APIKey{
+			Token:     cfg.AdminToken,
+			Principal: Principal{Subject: adminScope, Scopes: []string{adminScope}},
+		})
+	}
+
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if auth == "" {
+			return c.Next()
+		}
+
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return unauthorized(c, "Expected a bearer token")
+		}
+
+		for i := range keys {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(keys[i].Token)) == 1 {
+				c.Locals(principalKey, &keys[i].Principal)
+				return c.Next()
+			}
+		}
+
+		return unauthorized(c, "Invalid API key")
+	}
+}
+
+// requireScopes rejects callers lacking any of the scopes. When no credentials
+// are configured only the admin scope is enforced, so the demo stays usable out
+// of the box while admin routes stay closed.
+func requireScopes(cfg Config, scopes ...string) fiber.Handler {
+	enabled := cfg.AdminToken != "" || len(cfg.APIKeys) > 0
+	admin := slices.Contains(scopes, adminScope)
+
+	return func(c *fiber.Ctx) error {
+		if !enabled {
+			if admin {
+				return c.Status(403).JSON(ErrorResponse{
+					Error:   "Forbidden",
+					Message: "Admin routes are disabled until ADMIN_TOKEN is set",
+				})
+			}
+			return c.Next()
+		}
+
+		p := currentPrincipal(c)
+		if p == nil {
+			return unauthorized(c, "Missing API key")
+		}
+
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				return c.Status(403).JSON(ErrorResponse{
+					Error:   "Forbidden",
+					Message: "Missing scope " + scope,
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// unauthorized responds with 401 and a bearer challenge
+func unauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return c.Status(401).JSON(ErrorResponse{
+		Error:   "Unauthorized",
+		Message: message,
+	})
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength is the minimum length accepted for secrets
+const minSecretLength = 32
+
+// Environments selected by APP_ENV
+const (
+	envDevelopment = "development"
+	envProduction  = "production"
+)
+
+// Config holds the application configuration, read from the environment
+type Config struct {
+	Env          string
+	RecorderSize int
+
+	Port        int
+	TLSCertFile string
+	TLSKeyFile  string
+	AdminToken  string
+	APIKeys     []APIKey
+
+	MonthlyQuota int
+
+	CaseSensitive         bool
+	StrictRouting         bool
+	RedirectTrailingSlash bool
+
+	DatabaseURL string
+	CacheURL    string
+	UploadDir   string
+
+	Sandbox              bool
+	SandboxUsers         int
+	SandboxResetInterval time.Duration
+
+	DiagnosticsDependencies []string
+	ClockReferenceURL       string
+
+	CORS map[string]CORSPolicy
+}
+
+// fileConfig is the layout of the optional JSON file named by CONFIG_FILE
+type fileConfig struct {
+	CORS map[string]CORSPolicy `json:"cors"`
+}
+
+// ConfigError reports every problem found in the configuration, grouped by section
+type ConfigError struct {
+	groups   []string
+	problems map[string][]string
+}
+
+// add records a problem in the given section
+func (e *ConfigError) add(group, format string, args ...interface{}) {
+	if e.problems == nil {
+		e.problems = make(map[string][]string)
+	}
+	if _, ok := e.problems[group]; !ok {
+		e.groups = append(e.groups, group)
+	}
+	e.problems[group] = append(e.problems[group], fmt.Sprintf(format, args...))
+}
+
+// count returns the number of problems recorded
+func (e *ConfigError) count() int {
+	n := 0
+	for _, problems := range e.problems {
+		n += len(problems)
+	}
+
+	return n
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+
+	n := e.count()
+	fmt.Fprintf(&b, "invalid configuration (%d problem", n)
+	if n != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString("):\n")
+
+	for _, group := range e.groups {
+		fmt.Fprintf(&b, "\n  %s:\n", group)
+		for _, problem := range e.problems[group] {
+			fmt.Fprintf(&b, "    - %s\n", problem)
+		}
+	}
+
+	return b.String()
+}
+
+// loadConfig reads the configuration from the environment and validates it,
+// reporting every problem at once rather than stopping at the first
+func loadConfig() (Config, error) {
+	errs := &ConfigError{}
+
+	cfg := Config{
+		Env:                     envOr("APP_ENV", envProduction),
+		RecorderSize:            envInt(errs, "development", "RECORDER_SIZE", 100),
+		Port:                    envInt(errs, "server", "PORT", 3000),
+		TLSCertFile:             os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:              os.Getenv("TLS_KEY_FILE"),
+		AdminToken:              os.Getenv("ADMIN_TOKEN"),
+		APIKeys:                 parseAPIKeys(errs, os.Getenv("API_KEYS")),
+		MonthlyQuota:            envInt(errs, "security", "MONTHLY_QUOTA", 0),
+		CaseSensitive:           envBool(errs, "routing", "CASE_SENSITIVE", false),
+		StrictRouting:           envBool(errs, "routing", "STRICT_ROUTING", false),
+		RedirectTrailingSlash:   envBool(errs, "routing", "REDIRECT_TRAILING_SLASH", false),
+		DatabaseURL:             os.Getenv("DATABASE_URL"),
+		CacheURL:                os.Getenv("CACHE_URL"),
+		UploadDir:               envOr("UPLOAD_DIR", "."),
+		Sandbox:                 envBool(errs, "sandbox", "SANDBOX", false),
+		SandboxUsers:            envInt(errs, "sandbox", "SANDBOX_USERS", 50),
+		SandboxResetInterval:    envDuration(errs, "sandbox", "SANDBOX_RESET_INTERVAL", time.Hour),
+		DiagnosticsDependencies: splitList(os.Getenv("DIAGNOSTICS_DEPENDENCIES")),
+		ClockReferenceURL:       os.Getenv("CLOCK_REFERENCE_URL"),
+		CORS:                    defaultCORSPolicies(),
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		cfg.loadFile(errs, path)
+	}
+
+	cfg.validate(errs)
+
+	if errs.count() > 0 {
+		return cfg, errs
+	}
+
+	return cfg, nil
+}
+
+// loadFile overrides the configuration with the JSON file at path
+func (cfg *Config) loadFile(errs *ConfigError, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		errs.add("config file", "CONFIG_FILE cannot be read: %v", err)
+		return
+	}
+	defer f.Close()
+
+	var fc fileConfig
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		errs.add("config file", "%s is not valid: %v", path, err)
+		return
+	}
+
+	names := make([]string, 0, len(fc.CORS))
+	for name := range fc.CORS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := cfg.CORS[name]; !ok {
+			errs.add("config file", "unknown CORS policy %q, expected one of %q, %q or %q", name, corsPublic, corsAPI, corsAdmin)
+			continue
+		}
+		cfg.CORS[name] = fc.CORS[name]
+	}
+}
+
+// validate records every problem with the configuration in errs
+func (cfg Config) validate(errs *ConfigError) {
+	// development
+	if cfg.Env != envDevelopment && cfg.Env != envProduction {
+		errs.add("development", "APP_ENV %q must be %q or %q", cfg.Env, envDevelopment, envProduction)
+	}
+	if cfg.RecorderSize < 1 {
+		errs.add("development", "RECORDER_SIZE %d must be at least 1", cfg.RecorderSize)
+	}
+
+	// server
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs.add("server", "PORT %d is out of range, must be between 1 and 65535", cfg.Port)
+	} else if ln, err := net.Listen("tcp", cfg.Addr()); err != nil {
+		errs.add("server", "PORT %d is not available: %v", cfg.Port, err)
+	} else {
+		ln.Close()
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		errs.add("server", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSCertFile != "" {
+		if _, err := os.Stat(cfg.TLSCertFile); err != nil {
+			errs.add("server", "TLS_CERT_FILE cannot be read: %v", err)
+		}
+	}
+	if cfg.TLSKeyFile != "" {
+		if _, err := os.Stat(cfg.TLSKeyFile); err != nil {
+			errs.add("server", "TLS_KEY_FILE cannot be read: %v", err)
+		}
+	}
+
+	// security
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minSecretLength {
+		errs.add("security", "ADMIN_TOKEN is %d characters long, must be at least %d", len(cfg.AdminToken), minSecretLength)
+	}
+	seen := map[string]bool{cfg.AdminToken: cfg.AdminToken != ""}
+	for _, key := range cfg.APIKeys {
+		if len(key.Token) < minSecretLength {
+			errs.add("security", "API_KEYS token for %q is %d characters long, must be at least %d", key.Subject, len(key.Token), minSecretLength)
+		}
+		if seen[key.Token] {
+			errs.add("security", "API_KEYS token for %q is already in use", key.Subject)
+		}
+		seen[key.Token] = true
+	}
+	if cfg.MonthlyQuota < 0 {
+		errs.add("security", "MONTHLY_QUOTA %d must not be negative", cfg.MonthlyQuota)
+	}
+
+	// storage
+	if cfg.DatabaseURL != "" {
+		if err := validateDialURL(cfg.DatabaseURL); err != nil {
+			errs.add("storage", "DATABASE_URL is not a valid DSN: %v", err)
+		}
+	}
+	if cfg.CacheURL != "" {
+		if err := validateDialURL(cfg.CacheURL); err != nil {
+			errs.add("storage", "CACHE_URL is not a valid URL: %v", err)
+		}
+	}
+	if info, err := os.Stat(cfg.UploadDir); err != nil {
+		errs.add("storage", "UPLOAD_DIR %q cannot be read: %v", cfg.UploadDir, err)
+	} else if !info.IsDir() {
+		errs.add("storage", "UPLOAD_DIR %q is not a directory", cfg.UploadDir)
+	}
+
+	// sandbox
+	if cfg.Sandbox {
+		if cfg.DatabaseURL != "" {
+			errs.add("sandbox", "SANDBOX and DATABASE_URL are mutually exclusive, the sandbox keeps its data in memory")
+		}
+		if cfg.SandboxUsers < 0 {
+			errs.add("sandbox", "SANDBOX_USERS %d must not be negative", cfg.SandboxUsers)
+		}
+		if cfg.SandboxResetInterval < time.Minute {
+			errs.add("sandbox", "SANDBOX_RESET_INTERVAL %s must be at least 1m", cfg.SandboxResetInterval)
+		}
+	}
+
+	// cors
+	for _, name := range []string{corsPublic, corsAPI, corsAdmin} {
+		cfg.CORS[name].validate(errs, name)
+	}
+
+	// diagnostics
+	for _, dep := range cfg.DiagnosticsDependencies {
+		if err := validateURL(dep); err != nil {
+			errs.add("diagnostics", "DIAGNOSTICS_DEPENDENCIES entry %q is not a valid URL: %v", dep, err)
+		}
+	}
+	if cfg.ClockReferenceURL != "" {
+		if err := validateURL(cfg.ClockReferenceURL); err != nil {
+			errs.add("diagnostics", "CLOCK_REFERENCE_URL is not a valid URL: %v", err)
+		}
+	}
+}
+
+// IsDevelopment reports whether the app runs in development mode
+func (cfg Config) IsDevelopment() bool {
+	return cfg.Env == envDevelopment
+}
+
+// Addr returns the address the server listens on
+func (cfg Config) Addr() string {
+	return fmt.Sprintf(":%d", cfg.Port)
+}
+
+// validateURL checks that s is an absolute URL with a host
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("missing scheme")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+
+	return nil
+}
+
+// validateDialURL checks that s is a URL the diagnostics can connect to: it
+// needs a port, or a scheme with a known default port
+func validateDialURL(s string) error {
+	if err := validateURL(s); err != nil {
+		return err
+	}
+
+	u, _ := url.Parse(s)
+	_, err := dialAddress(u)
+	return err
+}
+
+// parseAPIKeys parses a comma separated list of "token:subject:scope scope"
+// entries. Malformed entries are recorded in errs.
+func parseAPIKeys(errs *ConfigError, s string) []APIKey {
+	var keys []APIKey
+	for i, entry := range splitList(s) {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			errs.add("security", "API_KEYS entry %d must have the form token:subject[:scope scope]", i+1)
+			continue
+		}
+
+		key := APIKey{Token: parts[0], Principal: Principal{Subject: parts[1]}}
+		if len(parts) == 3 {
+			key.Scopes = strings.Fields(parts[2])
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// envOr returns the value of the environment variable key, or fallback when unset
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+// envInt returns the integer value of the environment variable key, or fallback
+// when unset. Unparseable values are recorded in errs under group.
+func envInt(errs *ConfigError, group, key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		errs.add(group, "%s %q is not a number", key, v)
+		return fallback
+	}
+
+	return n
+}
+
+// envDuration returns the duration value of the environment variable key, or
+// fallback when unset. Unparseable values are recorded in errs under group.
+func envDuration(errs *ConfigError, group, key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		errs.add(group, "%s %q is not a duration such as 30m or 1h", key, v)
+		return fallback
+	}
+
+	return d
+}
+
+// envBool returns the boolean value of the environment variable key, or fallback
+// when unset. Unparseable values are recorded in errs under group.
+func envBool(errs *ConfigError, group, key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		errs.add(group, "%s %q is not a boolean", key, v)
+		return fallback
+	}
+
+	return b
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
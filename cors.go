@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORS policy names, one per route group
+const (
+	corsPublic = "public"
+	corsAPI    = "api"
+	corsAdmin  = "admin"
+)
+
+// CORSPolicy configures cross-origin access to a route group
+type CORSPolicy struct {
+	AllowOrigins     []string `json:"allow_origins"`
+	AllowMethods     []string `json:"allow_methods"`
+	AllowHeaders     []string `json:"allow_headers"`
+	ExposeHeaders    []string `json:"expose_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           int      `json:"max_age"`
+}
+
+// defaultCORSPolicies returns the policies used when the config file does not override them:
+// public and API routes are open to any origin, admin routes to none
+func defaultCORSPolicies() map[string]CORSPolicy {
+	return map[string]CORSPolicy{
+		corsPublic: {
+			AllowOrigins:  []string{"*"},
+			AllowMethods:  []string{fiber.MethodGet, fiber.MethodHead},
+			ExposeHeaders: []string{versionHeader},
+		},
+		corsAPI: {
+			AllowOrigins:  []string{"*"},
+			AllowMethods:  []string{fiber.MethodGet, fiber.MethodHead, fiber.MethodPost, fiber.MethodPut, fiber.MethodDelete},
+			AllowHeaders:  []string{fiber.HeaderContentType, fiber.HeaderAuthorization},
+			ExposeHeaders: []string{versionHeader, fiber.HeaderLink, totalCountHeader},
+		},
+		corsAdmin: {},
+	}
+}
+
+// validate records every problem with the policy in errs. Origins are checked
+// the way the cors middleware does, which panics on the ones it rejects.
+func (p CORSPolicy) validate(errs *ConfigError, name string) {
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			if len(p.AllowOrigins) > 1 {
+				errs.add("cors", "policy %q cannot combine any origin (\"*\") with other origins", name)
+			}
+			if p.AllowCredentials {
+				errs.add("cors", "policy %q cannot allow credentials for any origin (\"*\"), list the origins instead", name)
+			}
+			continue
+		}
+
+		// A leading "*." in the host allows every subdomain
+		host := origin
+		if i := strings.Index(host, "://*."); i >= 0 {
+			host = host[:i+3] + host[i+5:]
+		}
+
+		u, err := url.Parse(strings.TrimSpace(host))
+		if err != nil || u.Scheme == "" || u.Host == "" || strings.ContainsAny(u.Host, "*,") ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			errs.add("cors", "policy %q origin %q must have the form scheme://host[:port]", name, origin)
+		}
+	}
+}
+
+// corsHandler returns the middleware enforcing the policy. A policy without
+// origins sends no CORS headers, so browsers refuse cross-origin requests.
+func corsHandler(p CORSPolicy) fiber.Handler {
+	if len(p.AllowOrigins) == 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(p.AllowOrigins, ","),
+		AllowMethods:     strings.Join(p.AllowMethods, ","),
+		AllowHeaders:     strings.Join(p.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(p.ExposeHeaders, ","),
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		invalid bool
+	}{
+		{"any origin", CORSPolicy{AllowOrigins: []string{"*"}}, false},
+		{"listed origins", CORSPolicy{AllowOrigins: []string{"https://a.example.com", "http://localhost:8080/"}, AllowCredentials: true}, false},
+		{"subdomain wildcard", CORSPolicy{AllowOrigins: []string{"https://*.example.com"}}, false},
+		{"no origins", CORSPolicy{}, false},
+		{"any origin with credentials", CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true}, true},
+		{"any origin among others", CORSPolicy{AllowOrigins: []string{"*", "https://a.example.com"}}, true},
+		{"query", CORSPolicy{AllowOrigins: []string{"https://a.example.com?x=1"}}, true},
+		{"fragment", CORSPolicy{AllowOrigins: []string{"https://a.example.com#f"}}, true},
+		{"path", CORSPolicy{AllowOrigins: []string{"https://a.example.com/app"}}, true},
+		{"no scheme", CORSPolicy{AllowOrigins: []string{"a.example.com"}}, true},
+		{"wildcard inside host", CORSPolicy{AllowOrigins: []string{"https://a*.example.com"}}, true},
+		{"comma separated entry", CORSPolicy{AllowOrigins: []string{"https://a.example.com,https://b.example.com"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := &ConfigError{}
+			tt.policy.validate(errs, "test")
+
+			if got := errs.count() > 0; got != tt.invalid {
+				t.Fatalf("invalid = %v, want %v: %v", got, tt.invalid, errs)
+			}
+			if tt.invalid {
+				return
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("corsHandler panicked on a policy that passed validation: %v", r)
+				}
+			}()
+			corsHandler(tt.policy)
+		})
+	}
+}
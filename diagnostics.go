@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CheckStatus is the outcome of a single diagnostic check
+type CheckStatus string
+
+const (
+	StatusPass CheckStatus = "pass"
+	StatusWarn CheckStatus = "warn"
+	StatusFail CheckStatus = "fail"
+	StatusSkip CheckStatus = "skip"
+)
+
+const (
+	diagnosticsTimeout = 5 * time.Second
+	slowLatency        = 500 * time.Millisecond
+	minFreeDiskBytes   = 1 << 30 // 1 GiB
+	maxClockSkew       = 2 * time.Second
+)
+
+// CheckResult represents the result of a single diagnostic check
+type CheckResult struct {
+	Name      string      `json:"name" example:"database"`
+	Status    CheckStatus `json:"status" example:"pass"`
+	Message   string      `json:"message" example:"connected in 3ms"`
+	LatencyMs int64       `json:"latency_ms" example:"3"`
+}
+
+// DiagnosticsReport represents the aggregated result of all diagnostic checks
+type DiagnosticsReport struct {
+	Status      CheckStatus   `json:"status" example:"pass"`
+	GeneratedAt time.Time     `json:"generated_at" example:"2024-01-01T00:00:00Z"`
+	Checks      []CheckResult `json:"checks"`
+}
+
+// diagnosticCheck is a named check run by the diagnostics endpoint
+type diagnosticCheck struct {
+	name string
+	run  func(ctx context.Context) CheckResult
+}
+
+// diagnosticChecks returns the battery of checks run by the diagnostics endpoint
+func diagnosticChecks(cfg Config) []diagnosticCheck {
+	return []diagnosticCheck{
+		{name: "database", run: func(ctx context.Context) CheckResult {
+			return checkDial(ctx, cfg.DatabaseURL)
+		}},
+		{name: "cache", run: func(ctx context.Context) CheckResult {
+			return checkDial(ctx, cfg.CacheURL)
+		}},
+		{name: "upload_disk_space", run: func(ctx context.Context) CheckResult {
+			return checkDiskSpace(cfg.UploadDir)
+		}},
+		{name: "dependencies", run: func(ctx context.Context) CheckResult {
+			return checkDependencies(ctx, cfg.DiagnosticsDependencies)
+		}},
+		{name: "clock_skew", run: func(ctx context.Context) CheckResult {
+			return checkClockSkew(ctx, cfg.ClockReferenceURL)
+		}},
+	}
+}
+
+// getDiagnostics returns a handler that runs every diagnostic check and reports
+// pass/warn/fail, responding with 503 when any check fails
+func getDiagnostics(cfg Config) fiber.Handler {
+	checks := diagnosticChecks(cfg)
+
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), diagnosticsTimeout)
+		defer cancel()
+
+		report := runDiagnostics(ctx, checks)
+
+		if report.Status == StatusFail {
+			return c.Status(503).JSON(report)
+		}
+
+		return c.JSON(report)
+	}
+}
+
+// runDiagnostics runs the checks concurrently and aggregates their results
+func runDiagnostics(ctx context.Context, checks []diagnosticCheck) DiagnosticsReport {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check diagnosticCheck) {
+			defer wg.Done()
+			start := time.Now()
+			result := check.run(ctx)
+			result.Name = check.name
+			if result.LatencyMs == 0 {
+				result.LatencyMs = time.Since(start).Milliseconds()
+			}
+			results[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	status := StatusPass
+	for _, result := range results {
+		switch {
+		case result.Status == StatusFail:
+			status = StatusFail
+		case result.Status == StatusWarn && status != StatusFail:
+			status = StatusWarn
+		}
+	}
+
+	return DiagnosticsReport{
+		Status:      status,
+		GeneratedAt: time.Now().UTC(),
+		Checks:      results,
+	}
+}
+
+// checkDial measures the TCP round-trip to the host of the given URL
+func checkDial(ctx context.Context, rawURL string) CheckResult {
+	if rawURL == "" {
+		return CheckResult{Status: StatusSkip, Message: "not configured"}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return CheckResult{Status: StatusFail, Message: "invalid URL"}
+	}
+	addr, err := dialAddress(u)
+	if err != nil {
+		return CheckResult{Status: StatusFail, Message: err.Error()}
+	}
+
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	latency := time.Since(start)
+	if err != nil {
+		return CheckResult{Status: StatusFail, Message: err.Error(), LatencyMs: latency.Milliseconds()}
+	}
+	conn.Close()
+
+	return latencyResult(latency, fmt.Sprintf("connected to %s in %s", addr, latency.Round(time.Millisecond)))
+}
+
+// defaultPorts holds the well-known port of the schemes accepted in DSNs
+var defaultPorts = map[string]string{
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"redis":      "6379",
+	"rediss":     "6379",
+	"mongodb":    "27017",
+	"memcached":  "11211",
+	"amqp":       "5672",
+	"amqps":      "5671",
+	"http":       "80",
+	"https":      "443",
+}
+
+// dialAddress returns the host:port to dial for u, falling back to the default
+// port of its scheme when the URL has none
+func dialAddress(u *url.URL) (string, error) {
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+
+	port, ok := defaultPorts[strings.ToLower(u.Scheme)]
+	if !ok {
+		return "", fmt.Errorf("no port in %s URL and no default port for its scheme", u.Scheme)
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// checkDiskSpace reports whether the upload directory has enough free space
+func checkDiskSpace(dir string) CheckResult {
+	free, err := freeDiskBytes(dir)
+	if err != nil {
+		return CheckResult{Status: StatusFail, Message: err.Error()}
+	}
+
+	message := fmt.Sprintf("%d MiB free in %s", free>>20, dir)
+	if free < minFreeDiskBytes {
+		return CheckResult{Status: StatusWarn, Message: message}
+	}
+
+	return CheckResult{Status: StatusPass, Message: message}
+}
+
+// checkDependencies verifies every external dependency answers without a server error
+func checkDependencies(ctx context.Context, urls []string) CheckResult {
+	if len(urls) == 0 {
+		return CheckResult{Status: StatusSkip, Message: "not configured"}
+	}
+
+	var failed []string
+	var slowest time.Duration
+	for _, u := range urls {
+		start := time.Now()
+		resp, err := httpHead(ctx, u)
+		latency := time.Since(start)
+		if latency > slowest {
+			slowest = latency
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", u, err))
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			failed = append(failed, fmt.Sprintf("%s: %s", u, resp.Status))
+		}
+	}
+
+	if len(failed) > 0 {
+		return CheckResult{Status: StatusFail, Message: strings.Join(failed, "; "), LatencyMs: slowest.Milliseconds()}
+	}
+
+	return latencyResult(slowest, fmt.Sprintf("%d reachable", len(urls)))
+}
+
+// checkClockSkew compares the local clock with the Date header of a reference server
+func checkClockSkew(ctx context.Context, referenceURL string) CheckResult {
+	if referenceURL == "" {
+		return CheckResult{Status: StatusSkip, Message: "not configured"}
+	}
+
+	start := time.Now()
+	resp, err := httpHead(ctx, referenceURL)
+	latency := time.Since(start)
+	if err != nil {
+		return CheckResult{Status: StatusFail, Message: err.Error(), LatencyMs: latency.Milliseconds()}
+	}
+
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return CheckResult{Status: StatusWarn, Message: "reference server sent no usable Date header", LatencyMs: latency.Milliseconds()}
+	}
+
+	// The Date header has a one second resolution, so compare against the
+	// midpoint of the request and allow for that imprecision.
+	skew := start.Add(latency / 2).Sub(remote)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	message := fmt.Sprintf("skew of %s against %s", skew.Round(time.Millisecond), referenceURL)
+	if skew > maxClockSkew {
+		return CheckResult{Status: StatusWarn, Message: message, LatencyMs: latency.Milliseconds()}
+	}
+
+	return CheckResult{Status: StatusPass, Message: message, LatencyMs: latency.Milliseconds()}
+}
+
+// httpHead issues a HEAD request bounded by ctx
+func httpHead(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// latencyResult passes fast checks and warns about slow ones
+func latencyResult(latency time.Duration, message string) CheckResult {
+	status := StatusPass
+	if latency > slowLatency {
+		status = StatusWarn
+	}
+
+	return CheckResult{Status: status, Message: message, LatencyMs: latency.Milliseconds()}
+}
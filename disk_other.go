@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+// freeDiskBytes is not supported outside unix platforms
+func freeDiskBytes(dir string) (uint64, error) {
+	return 0, errors.New("disk space check not supported on " + runtime.GOOS)
+}
@@ -23,9 +23,35 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/me/usage": {
+            "get": {
+                "description": "Get the authenticated caller's consumption of every rate limit and of the monthly quota, so clients can throttle themselves",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Get the caller's usage",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.UsageReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/users": {
             "get": {
-                "description": "Get a list of all users",
+                "description": "Get a list of users, optionally filtered",
                 "consumes": [
                     "application/json"
                 ],
@@ -45,11 +71,18 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
+                        "maximum": 100,
                         "type": "integer",
                         "default": 10,
                         "description": "Number of items per page",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RSQL/FIQL filter on id, name and age, e.g. age\u003e=18;name==John*",
+                        "name": "filter",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -60,6 +93,22 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/main.User"
                             }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Links to the next, prev, first and last pages (RFC 5988)"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Total number of users"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -111,6 +160,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -206,6 +261,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             },
@@ -290,6 +351,50 @@ const docTemplate = `{
                 }
             }
         },
+        "main.QuotaUsage": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 10000
+                },
+                "remaining": {
+                    "type": "integer",
+                    "example": 8766
+                },
+                "reset_at": {
+                    "type": "string"
+                },
+                "used": {
+                    "type": "integer",
+                    "example": 1234
+                }
+            }
+        },
+        "main.RateLimitUsage": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "remaining": {
+                    "type": "integer",
+                    "example": 88
+                },
+                "reset_at": {
+                    "type": "string"
+                },
+                "route": {
+                    "type": "string",
+                    "example": "GET /api/v1/users"
+                },
+                "used": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
         "main.SuccessResponse": {
             "type": "object",
             "properties": {
@@ -300,6 +405,24 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UsageReport": {
+            "type": "object",
+            "properties": {
+                "monthly_quota": {
+                    "$ref": "#/definitions/main.QuotaUsage"
+                },
+                "rate_limits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RateLimitUsage"
+                    }
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "mobile-app"
+                }
+            }
+        },
         "main.User": {
             "type": "object",
             "properties": {
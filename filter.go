@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits on filter expressions, so a single request cannot make matching arbitrarily expensive
+const (
+	maxFilterLength = 1024
+	maxFilterDepth  = 5
+	maxFilterTerms  = 20
+)
+
+// filterField describes a field that can be filtered on
+type filterField struct {
+	numeric bool
+	value   func(User) string
+}
+
+// userFilterFields is the whitelist of filterable user fields. Masked fields
+// such as email are left out so filters cannot reveal their values.
+var userFilterFields = map[string]filterField{
+	"id":   {numeric: true, value: func(u User) string { return strconv.Itoa(u.ID) }},
+	"name": {value: func(u User) string { return u.Name }},
+	"age":  {numeric: true, value: func(u User) string { return strconv.Itoa(u.Age) }},
+}
+
+// filterOperators maps the RSQL/FIQL comparison operators to their canonical form
+var filterOperators = map[string]string{
+	"==": "==", "!=": "!=",
+	"=gt=": ">", ">": ">",
+	"=ge=": ">=", ">=": ">=",
+	"=lt=": "<", "<": "<",
+	"=le=": "<=", "<=": "<=",
+	"=in=": "=in=", "=out=": "=out=",
+}
+
+// userPredicate reports whether a user matches a filter
+type userPredicate func(User) bool
+
+// parseUserFilter parses an RSQL/FIQL expression such as
+// "age>=18;(name==John*,name==Jane*)" into a predicate. ";" is AND, "," is OR
+// and binds looser, and "*" is a wildcard in == and != on text fields.
+// An empty expression matches every user.
+func parseUserFilter(expr string) (userPredicate, error) {
+	if strings.TrimSpace(expr) == "" {
+		return func(User) bool { return true }, nil
+	}
+	if len(expr) > maxFilterLength {
+		return nil, fmt.Errorf("filter must be at most %d characters", maxFilterLength)
+	}
+
+	p := &filterParser{input: expr}
+	pred, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("filter: unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+
+	return pred, nil
+}
+
+// filterParser is a recursive descent parser over a filter expression
+type filterParser struct {
+	input string
+	pos   int
+	terms int
+}
+
+// parseOr parses comparisons joined by ","
+func (p *filterParser) parseOr(depth int) (userPredicate, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(',') {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(u User) bool { return l(u) || right(u) }
+	}
+
+	return left, nil
+}
+
+// parseAnd parses comparisons joined by ";"
+func (p *filterParser) parseAnd(depth int) (userPredicate, error) {
+	left, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(';') {
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(u User) bool { return l(u) && right(u) }
+	}
+
+	return left, nil
+}
+
+// parseTerm parses a parenthesized group or a single comparison
+func (p *filterParser) parseTerm(depth int) (userPredicate, error) {
+	if p.accept('(') {
+		if depth+1 > maxFilterDepth {
+			return nil, fmt.Errorf("filter must not nest groups more than %d levels deep", maxFilterDepth)
+		}
+		pred, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, fmt.Errorf("filter: missing ')' at position %d", p.pos)
+		}
+		return pred, nil
+	}
+
+	p.terms++
+	if p.terms > maxFilterTerms {
+		return nil, fmt.Errorf("filter must have at most %d comparisons", maxFilterTerms)
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses selector, operator and arguments
+func (p *filterParser) parseComparison() (userPredicate, error) {
+	start := p.pos
+	for p.pos < len(p.input) && isSelectorChar(p.input[p.pos]) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	if name == "" {
+		return nil, fmt.Errorf("filter: expected a field name at position %d", start)
+	}
+	field, ok := userFilterFields[name]
+	if !ok {
+		return nil, fmt.Errorf("filter: unknown field %q, expected one of %s", name, strings.Join(sortedKeys(userFilterFields), ", "))
+	}
+
+	op, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if op == "=in=" || op == "=out=" {
+		if args, err = p.parseList(); err != nil {
+			return nil, err
+		}
+	} else {
+		arg, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		args = []string{arg}
+	}
+
+	return comparison(name, field, op, args)
+}
+
+// parseOperator parses one of filterOperators
+func (p *filterParser) parseOperator() (string, error) {
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, "=") && !strings.HasPrefix(rest, "==") {
+		if end := strings.IndexByte(rest[1:], '='); end >= 0 {
+			if op, ok := filterOperators[rest[:end+2]]; ok {
+				p.pos += end + 2
+				return op, nil
+			}
+		}
+	}
+	for _, candidate := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if strings.HasPrefix(rest, candidate) {
+			p.pos += len(candidate)
+			return filterOperators[candidate], nil
+		}
+	}
+
+	return "", fmt.Errorf("filter: expected an operator at position %d", p.pos)
+}
+
+// parseList parses a parenthesized, comma-separated list of values
+func (p *filterParser) parseList() ([]string, error) {
+	if !p.accept('(') {
+		return nil, fmt.Errorf("filter: expected '(' at position %d", p.pos)
+	}
+
+	var values []string
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if !p.accept(',') {
+			break
+		}
+	}
+	if !p.accept(')') {
+		return nil, fmt.Errorf("filter: missing ')' at position %d", p.pos)
+	}
+
+	return values, nil
+}
+
+// parseValue parses a single or double quoted string or an unreserved word
+func (p *filterParser) parseValue() (string, error) {
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		quote := p.input[p.pos]
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return "", fmt.Errorf("filter: unterminated string at position %d", p.pos)
+		}
+		v := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return v, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(`;,()"'`, rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", fmt.Errorf("filter: expected a value at position %d", start)
+	}
+
+	return p.input[start:p.pos], nil
+}
+
+// accept consumes b if it is the next byte
+func (p *filterParser) accept(b byte) bool {
+	if p.pos < len(p.input) && p.input[p.pos] == b {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// isSelectorChar reports whether b can appear in a field name
+func isSelectorChar(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// comparison builds the predicate comparing a field to its arguments
+func comparison(name string, field filterField, op string, args []string) (userPredicate, error) {
+	if field.numeric {
+		nums := make([]int, len(args))
+		for i, arg := range args {
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("filter: %s must be compared to an integer, got %q", name, arg)
+			}
+			nums[i] = n
+		}
+
+		return func(u User) bool {
+			v, _ := strconv.Atoi(field.value(u))
+			return compareInts(v, op, nums)
+		}, nil
+	}
+
+	switch op {
+	case "==", "!=", "=in=", "=out=":
+	default:
+		return nil, fmt.Errorf("filter: %s only supports ==, !=, =in= and =out=", name)
+	}
+	return func(u User) bool {
+		v := field.value(u)
+		matched := false
+		for _, arg := range args {
+			if matchWildcard(arg, v) {
+				matched = true
+				break
+			}
+		}
+		return matched == (op == "==" || op == "=in=")
+	}, nil
+}
+
+// matchWildcard reports whether s matches pattern, where "*" matches any run of characters
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return s == pattern
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+
+	return strings.HasSuffix(s, last)
+}
+
+// compareInts applies a canonical operator to v and its arguments
+func compareInts(v int, op string, args []int) bool {
+	switch op {
+	case "==":
+		return v == args[0]
+	case "!=":
+		return v != args[0]
+	case ">":
+		return v > args[0]
+	case ">=":
+		return v >= args[0]
+	case "<":
+		return v < args[0]
+	case "<=":
+		return v <= args[0]
+	}
+
+	in := false
+	for _, a := range args {
+		if v == a {
+			in = true
+			break
+		}
+	}
+
+	return in == (op == "=in=")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var filterFixture = []User{
+	{ID: 1, Name: "John Doe", Age: 30},
+	{ID: 2, Name: "Jane Smith", Age: 25},
+	{ID: 3, Name: "Johnny Walker", Age: 17},
+	{ID: 4, Name: "Mary-Jane O'Neil", Age: 40},
+	{ID: 5, Name: "Ann, Lee", Age: 22},
+}
+
+func TestParseUserFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []int
+	}{
+		{"", []int{1, 2, 3, 4, 5}},
+		{"age>=18", []int{1, 2, 4, 5}},
+		{"age=ge=18", []int{1, 2, 4, 5}},
+		{"age=lt=25", []int{3, 5}},
+		{"age<=25", []int{2, 3, 5}},
+		{"age=gt=30", []int{4}},
+		{"age!=30", []int{2, 3, 4, 5}},
+		{"id=in=(1,3,5)", []int{1, 3, 5}},
+		{"id=out=(1,3,5)", []int{2, 4}},
+
+		// wildcards
+		{"name==John*", []int{1, 3}},
+		{"name==*Jane*", []int{2, 4}},
+		{"name==J*n*", []int{1, 2, 3}},
+		{"name!=J*", []int{4, 5}},
+		{"name=in=(John*,Ann*)", []int{1, 3, 5}},
+
+		// quoting
+		{"name=='John Doe'", []int{1}},
+		{`name=="Ann, Lee"`, []int{5}},
+		{`name=="Mary-Jane O'Neil"`, []int{4}},
+
+		// ";" binds tighter than ","
+		{"age>35,name==J*;age<20", []int{3, 4}},
+		{"name==J*;age<20,age>35", []int{3, 4}},
+		{"(age>35,name==J*);age<20", []int{3}},
+		{"(((((id==1)))))", []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			match, err := parseUserFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := []int{}
+			for _, u := range filterFixture {
+				if match(u) {
+					got = append(got, u.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserFilterErrors(t *testing.T) {
+	tests := []struct {
+		name, expr, want string
+	}{
+		{"unknown field", "email==john@example.com", `unknown field "email"`},
+		{"non-integer", "age>abc", "age must be compared to an integer"},
+		{"ordering on text", "name>a", "name only supports"},
+		{"unknown operator", "id=foo=1", "expected an operator"},
+		{"missing operator", "id", "expected an operator"},
+		{"missing value", "id==", "expected a value"},
+		{"dangling separator", "id==1;", "expected a field name"},
+		{"unterminated string", "name=='John", "unterminated string"},
+		{"unclosed list", "id=in=(1,2", "missing ')'"},
+		{"unclosed group", "(id==1", "missing ')'"},
+		{"trailing input", "id==1)", "unexpected"},
+		{"too deep", "((((((id==1))))))", "more than 5 levels deep"},
+		{"too many terms", strings.Repeat("id==1;", 20) + "id==1", "at most 20 comparisons"},
+		{"too long", "name==" + strings.Repeat("a", maxFilterLength), "at most 1024 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseUserFilter(tt.expr)
+			if err == nil {
+				t.Fatalf("parseUserFilter(%q) succeeded, want an error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"John", "John", true},
+		{"John", "Johnny", false},
+		{"John*", "Johnny", true},
+		{"*ny", "Johnny", true},
+		{"*", "", true},
+		{"J*n*y", "Johnny", true},
+		{"*a*a", "a", false},
+		{"a*a", "a", false},
+		{"a/b*", "a/bc", true},
+		{"a?c", "abc", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
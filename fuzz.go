@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// swaggerDoc is the subset of a Swagger 2.0 document used by the fuzzer
+type swaggerDoc struct {
+	BasePath    string                          `json:"basePath"`
+	Paths       map[string]map[string]operation `json:"paths"`
+	Definitions map[string]*schema              `json:"definitions"`
+}
+
+type operation struct {
+	Parameters []parameter          `json:"parameters"`
+	Responses  map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Type     string      `json:"type"`
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default"`
+	Minimum  *float64    `json:"minimum"`
+	Schema   *schema     `json:"schema"`
+}
+
+type response struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Properties map[string]*schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *schema            `json:"items"`
+	Minimum    *float64           `json:"minimum"`
+	Example    interface{}        `json:"example"`
+}
+
+// fuzzCase is a single generated request
+type fuzzCase struct {
+	name   string
+	params map[string]string
+	query  url.Values
+	body   []byte
+}
+
+// fuzzFinding is a response that breaks the robustness contract
+type fuzzFinding struct {
+	method, path, name string
+	status             int
+	problem            string
+}
+
+// fuzzer fires generated requests at a running instance
+type fuzzer struct {
+	doc    swaggerDoc
+	target string
+	token  string
+	runs   int
+	rnd    *rand.Rand
+	client *http.Client
+}
+
+// runFuzzAPI implements the fuzz-api subcommand and returns the process exit code
+func runFuzzAPI(args []string) int {
+	fs := flag.NewFlagSet("fuzz-api", flag.ContinueOnError)
+	specPath := fs.String("spec", "docs/swagger.json", "path or URL of the OpenAPI document")
+	target := fs.String("target", "http://localhost:3000", "base URL of the running instance")
+	token := fs.String("token", "", "bearer token sent with every request")
+	runs := fs.Int("runs", 10, "random valid requests per operation")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for reproducing a run")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	doc, err := loadSwaggerDoc(*specPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fuzz-api:", err)
+		return 2
+	}
+
+	f := &fuzzer{
+		doc:    doc,
+		target: strings.TrimRight(*target, "/"),
+		token:  *token,
+		runs:   *runs,
+		rnd:    rand.New(rand.NewSource(*seed)),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	fmt.Printf("fuzzing %s with seed %d\n", f.target, *seed)
+	sent, findings := f.run()
+
+	for _, finding := range findings {
+		fmt.Printf("FAIL %s %s [%s] -> %d: %s\n", finding.method, finding.path, finding.name, finding.status, abbreviate(finding.problem))
+	}
+	fmt.Printf("%d requests, %d findings\n", sent, len(findings))
+
+	if len(findings) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// loadSwaggerDoc reads the document from a file or an http(s) URL
+func loadSwaggerDoc(location string) (swaggerDoc, error) {
+	var doc swaggerDoc
+
+	var r io.ReadCloser
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := http.Get(location)
+		if err != nil {
+			return doc, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return doc, fmt.Errorf("fetching %s: %s", location, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return doc, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return doc, fmt.Errorf("parsing %s: %w", location, err)
+	}
+
+	return doc, nil
+}
+
+// run sends every generated case for every operation, in a stable order
+func (f *fuzzer) run() (int, []fuzzFinding) {
+	var findings []fuzzFinding
+	sent := 0
+
+	paths := make([]string, 0, len(f.doc.Paths))
+	for p := range f.doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		methods := make([]string, 0, len(f.doc.Paths[p]))
+		for m := range f.doc.Paths[p] {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+
+		for _, m := range methods {
+			op := f.doc.Paths[p][m]
+			for _, fc := range f.cases(op) {
+				sent++
+				method := strings.ToUpper(m)
+				status, problem := f.send(method, p, op, fc)
+				if problem != "" {
+					findings = append(findings, fuzzFinding{method: method, path: p, name: fc.name, status: status, problem: problem})
+				}
+				if problem == errRateLimited {
+					return sent, findings
+				}
+			}
+		}
+	}
+
+	return sent, findings
+}
+
+// cases generates random valid requests followed by boundary-invalid variants
+func (f *fuzzer) cases(op operation) []fuzzCase {
+	valid := func(name string) fuzzCase {
+		fc := fuzzCase{name: name, params: map[string]string{}, query: url.Values{}}
+		for _, p := range op.Parameters {
+			switch p.In {
+			case "path":
+				fc.params[p.Name] = f.validParam(p)
+			case "query":
+				if p.Required || f.rnd.Intn(2) == 0 {
+					fc.query.Set(p.Name, f.validParam(p))
+				}
+			case "body":
+				fc.body, _ = json.Marshal(f.validValue(p.Schema, 0))
+			}
+		}
+		return fc
+	}
+
+	var cases []fuzzCase
+	for i := 0; i < f.runs; i++ {
+		cases = append(cases, valid(fmt.Sprintf("valid #%d", i+1)))
+	}
+
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path", "query":
+			for _, v := range invalidScalars(p.Type, p.Minimum) {
+				// An empty path segment would address a different route
+				if p.In == "path" && v == "" {
+					continue
+				}
+				fc := valid(fmt.Sprintf("%s %s=%q", p.In, p.Name, abbreviate(v)))
+				if p.In == "path" {
+					fc.params[p.Name] = url.PathEscape(v)
+				} else {
+					fc.query.Set(p.Name, v)
+				}
+				cases = append(cases, fc)
+			}
+		case "body":
+			for _, raw := range []string{"", "{", "null", "[]", `"text"`, "{}"} {
+				fc := valid(fmt.Sprintf("body %q", raw))
+				fc.body = []byte(raw)
+				cases = append(cases, fc)
+			}
+			s := f.resolve(p.Schema)
+			for _, prop := range sortedKeys(s.Properties) {
+				propSchema := f.resolve(s.Properties[prop])
+				invalid := append([]interface{}{nil, []interface{}{}, map[string]interface{}{}}, invalidJSONValues(propSchema)...)
+				for _, v := range invalid {
+					obj, _ := f.validValue(p.Schema, 0).(map[string]interface{})
+					if obj == nil {
+						continue
+					}
+					obj[prop] = v
+					fc := valid(fmt.Sprintf("body %s=%s", prop, abbreviate(fmt.Sprint(v))))
+					fc.body, _ = json.Marshal(obj)
+					cases = append(cases, fc)
+				}
+				for _, req := range s.Required {
+					if req != prop {
+						continue
+					}
+					obj, _ := f.validValue(p.Schema, 0).(map[string]interface{})
+					delete(obj, prop)
+					fc := valid(fmt.Sprintf("body without %s", prop))
+					fc.body, _ = json.Marshal(obj)
+					cases = append(cases, fc)
+				}
+			}
+		}
+	}
+
+	return cases
+}
+
+// send issues the request and returns its status and any problem found
+func (f *fuzzer) send(method, path string, op operation, fc fuzzCase) (int, string) {
+	for name, value := range fc.params {
+		path = strings.ReplaceAll(path, "{"+name+"}", value)
+	}
+	u := f.target + f.doc.BasePath + path
+	if len(fc.query) > 0 {
+		u += "?" + fc.query.Encode()
+	}
+
+	resp, err := f.do(method, u, fc.body)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return resp.StatusCode, errRateLimited
+	}
+	if resp.StatusCode >= 500 {
+		return resp.StatusCode, "server error"
+	}
+
+	declared, ok := op.Responses[strconv.Itoa(resp.StatusCode)]
+	if !ok {
+		return resp.StatusCode, "undocumented status code"
+	}
+	if declared.Schema == nil {
+		return resp.StatusCode, ""
+	}
+
+	var payload interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return resp.StatusCode, "response is not valid JSON: " + err.Error()
+	}
+	if problems := f.validate(declared.Schema, payload, "$", 0); len(problems) > 0 {
+		return resp.StatusCode, "response violates schema: " + strings.Join(problems, "; ")
+	}
+
+	return resp.StatusCode, ""
+}
+
+// Backoff applied when the target rate limits the fuzzer
+const (
+	maxRateLimitRetries = 3
+	maxRateLimitWait    = time.Minute
+)
+
+// errRateLimited is the problem reported when the target keeps answering 429,
+// after which the run stops since every further request would be rejected too
+const errRateLimited = "still rate limited after backing off"
+
+// do sends a request, waiting as told by Retry-After and retrying when the
+// target answers 429 so rate limits are not mistaken for results
+func (f *fuzzer) do(method, u string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, u, r)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if f.token != "" {
+			req.Header.Set("Authorization", "Bearer "+f.token)
+		}
+
+		resp, err := f.client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, err
+		}
+
+		wait := time.Second
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			wait = min(time.Duration(secs)*time.Second, maxRateLimitWait)
+		}
+		resp.Body.Close()
+
+		fmt.Printf("rate limited, waiting %s\n", wait)
+		time.Sleep(wait)
+	}
+}
+
+// maxSchemaDepth stops runaway recursion through self-referencing definitions
+const maxSchemaDepth = 16
+
+// resolve follows a "#/definitions/..." reference
+func (f *fuzzer) resolve(s *schema) *schema {
+	if s == nil {
+		return &schema{}
+	}
+	if name, ok := strings.CutPrefix(s.Ref, "#/definitions/"); ok {
+		if def, ok := f.doc.Definitions[name]; ok {
+			return def
+		}
+	}
+
+	return s
+}
+
+// validate returns every way value fails to match the schema
+func (f *fuzzer) validate(s *schema, value interface{}, at string, depth int) []string {
+	s = f.resolve(s)
+	if depth > maxSchemaDepth || s.Type == "" && s.Properties == nil {
+		return nil
+	}
+
+	mismatch := func() []string {
+		return []string{fmt.Sprintf("%s: expected %s, got %T", at, s.Type, value)}
+	}
+
+	switch s.Type {
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		var problems []string
+		for i, item := range items {
+			problems = append(problems, f.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i), depth+1)...)
+		}
+		return problems
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return mismatch()
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return mismatch()
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return mismatch()
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return mismatch()
+		}
+	default:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		var problems []string
+		for _, req := range s.Required {
+			if _, ok := obj[req]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: required", at, req))
+			}
+		}
+		for _, name := range sortedKeys(s.Properties) {
+			if v, ok := obj[name]; ok && v != nil {
+				problems = append(problems, f.validate(s.Properties[name], v, at+"."+name, depth+1)...)
+			}
+		}
+		return problems
+	}
+
+	return nil
+}
+
+// validValue generates a value matching the schema, preferring its example
+func (f *fuzzer) validValue(s *schema, depth int) interface{} {
+	s = f.resolve(s)
+	if depth > maxSchemaDepth {
+		return nil
+	}
+	if s.Example != nil && f.rnd.Intn(2) == 0 {
+		return s.Example
+	}
+
+	switch s.Type {
+	case "array":
+		return []interface{}{f.validValue(s.Items, depth+1)}
+	case "integer":
+		min := 0
+		if s.Minimum != nil {
+			min = int(*s.Minimum)
+		}
+		return min + f.rnd.Intn(100)
+	case "number":
+		return f.rnd.Float64() * 100
+	case "string":
+		return randomString(f.rnd)
+	case "boolean":
+		return f.rnd.Intn(2) == 0
+	default:
+		obj := map[string]interface{}{}
+		for _, name := range sortedKeys(s.Properties) {
+			obj[name] = f.validValue(s.Properties[name], depth+1)
+		}
+		return obj
+	}
+}
+
+// validParam generates a valid path or query parameter
+func (f *fuzzer) validParam(p parameter) string {
+	if p.Default != nil && f.rnd.Intn(2) == 0 {
+		return fmt.Sprint(p.Default)
+	}
+
+	switch p.Type {
+	case "integer", "number":
+		min := 1
+		if p.Minimum != nil {
+			min = int(*p.Minimum)
+		}
+		return strconv.Itoa(min + f.rnd.Intn(100))
+	case "boolean":
+		return strconv.FormatBool(f.rnd.Intn(2) == 0)
+	default:
+		return url.QueryEscape(randomString(f.rnd))
+	}
+}
+
+// invalidScalars returns boundary and malformed values for a path or query parameter
+func invalidScalars(typ string, minimum *float64) []string {
+	values := []string{"", " ", "%00", strings.Repeat("a", 1024), "' OR 1=1 --", "☃"}
+	if typ == "integer" || typ == "number" {
+		values = append(values, "-1", "0", "1.5", "abc", "1e309", "9223372036854775808", "-9223372036854775809")
+		if minimum != nil {
+			values = append(values, strconv.FormatFloat(*minimum-1, 'f', -1, 64))
+		}
+	}
+
+	return values
+}
+
+// invalidJSONValues returns boundary and wrongly typed values for a body property
+func invalidJSONValues(s *schema) []interface{} {
+	values := []interface{}{true}
+	switch s.Type {
+	case "integer", "number":
+		values = append(values, "1", -1, 0, 1.5, math.MaxInt64, -math.MaxInt64, 1e300)
+		if s.Minimum != nil {
+			values = append(values, *s.Minimum-1)
+		}
+	case "string":
+		values = append(values, 1, "", strings.Repeat("a", 1<<16), "\u0000", "☃")
+	default:
+		values = append(values, 1, "text")
+	}
+
+	return values
+}
+
+// randomString returns a short random alphanumeric string
+func randomString(rnd *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, 1+rnd.Intn(16))
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+
+	return string(b)
+}
+
+// abbreviate shortens s for display
+func abbreviate(s string) string {
+	const max = 120
+	if len(s) <= max {
+		return s
+	}
+
+	return s[:max] + "..."
+}
+
+// sortedKeys returns the keys of m in order, for reproducible runs
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// limits backs the rate limiters and the monthly quota, so the usage of a
+// caller can be reported from the counters that are actually enforced
+var limits = newLimitStore()
+
+// limitStore is an in-memory fiber.Storage that also knows the rate limit of every route
+type limitStore struct {
+	mu        sync.Mutex
+	entries   map[string]limitEntry
+	lastSweep time.Time
+
+	routes map[string]RateLimit
+}
+
+type limitEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// newLimitStore returns an empty store
+func newLimitStore() *limitStore {
+	return &limitStore{
+		entries: make(map[string]limitEntry),
+		routes:  make(map[string]RateLimit),
+	}
+}
+
+// register records the rate limit of a route. It must be called before the server starts.
+func (s *limitStore) register(route string, limit RateLimit) {
+	s.routes[route] = limit
+}
+
+// rateLimitKey is the storage key of a caller's counters for a route
+func rateLimitKey(route, caller string) string {
+	return "rate|" + caller + "|" + route
+}
+
+// quotaKey is the storage key of a caller's quota counter for the month of now
+func quotaKey(caller string, now time.Time) string {
+	return "quota|" + caller + "|" + now.UTC().Format("2006-01")
+}
+
+// Get returns the value stored for key, nil if there is none
+func (s *limitStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !e.expires.IsZero() && time.Now().After(e.expires) {
+		return nil, nil
+	}
+
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set stores val for key, forgetting it after exp unless exp is 0
+func (s *limitStore) Set(key string, val []byte, exp time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expires time.Time
+	if exp > 0 {
+		expires = time.Now().Add(exp)
+	}
+	s.entries[key] = limitEntry{value: append([]byte(nil), val...), expires: expires}
+	s.sweep()
+
+	return nil
+}
+
+// Delete forgets key
+func (s *limitStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Reset forgets every key
+func (s *limitStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make(map[string]limitEntry)
+	return nil
+}
+
+// Close is a no-op, the store lives as long as the process
+func (s *limitStore) Close() error {
+	return nil
+}
+
+// sweep drops expired entries, at most once a minute. s.mu must be held.
+func (s *limitStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// consume adds one to the counter stored under key unless it already reached
+// max, reporting whether it did
+func (s *limitStore) consume(key string, max int, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	if e, ok := s.entries[key]; ok && time.Now().Before(e.expires) {
+		n, _ = strconv.Atoi(string(e.value))
+	}
+	if n >= max {
+		return false
+	}
+	s.entries[key] = limitEntry{value: []byte(strconv.Itoa(n + 1)), expires: expires}
+	s.sweep()
+
+	return true
+}
+
+// quota returns the middleware counting requests against the caller's quota for
+// the calendar month (UTC) and rejecting them once max is reached
+func (s *limitStore) quota(max int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		now := time.Now()
+		if !s.consume(quotaKey(callerKey(c), now), max, monthEnd(now)) {
+			return c.Status(429).JSON(ErrorResponse{
+				Error:   "Too Many Requests",
+				Message: "Monthly quota exceeded",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// quotaUsed returns how many requests the caller made this month
+func (s *limitStore) quotaUsed(caller string, now time.Time) int {
+	raw, _ := s.Get(quotaKey(caller, now))
+	n, _ := strconv.Atoi(string(raw))
+	return n
+}
+
+// rateLimitUsage reports the caller's current window on every rate limited route
+func (s *limitStore) rateLimitUsage(caller string, now time.Time) []RateLimitUsage {
+	routes := make([]string, 0, len(s.routes))
+	for route := range s.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	out := make([]RateLimitUsage, 0, len(routes))
+	for _, route := range routes {
+		usage := RateLimitUsage{Route: route, Limit: s.routes[route].Max, Remaining: s.routes[route].Max}
+
+		raw, _ := s.Get(rateLimitKey(route, caller))
+		if hits, reset, err := decodeLimiterEntry(raw); err == nil && reset.After(now) {
+			usage.Used = hits
+			usage.Remaining = max(0, usage.Limit-hits)
+			usage.ResetAt = &reset
+		}
+
+		out = append(out, usage)
+	}
+
+	return out
+}
+
+// decodeLimiterEntry reads the counters the limiter middleware stores for a
+// fixed window: a MessagePack map holding currHits and exp, in unix seconds
+func decodeLimiterEntry(raw []byte) (int, time.Time, error) {
+	if raw == nil {
+		return 0, time.Time{}, fmt.Errorf("no entry")
+	}
+
+	n, b, err := msgp.ReadMapHeaderBytes(raw)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var hits int
+	var exp uint64
+	for ; n > 0; n-- {
+		var key []byte
+		if key, b, err = msgp.ReadMapKeyZC(b); err != nil {
+			return 0, time.Time{}, err
+		}
+
+		switch string(key) {
+		case "currHits":
+			hits, b, err = msgp.ReadIntBytes(b)
+		case "exp":
+			exp, b, err = msgp.ReadUint64Bytes(b)
+		default:
+			b, err = msgp.Skip(b)
+		}
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	return hits, time.Unix(int64(exp), 0).UTC(), nil
+}
+
+// monthEnd returns the start of the calendar month (UTC) after now
+func monthEnd(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
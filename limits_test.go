@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// TestRateLimitUsageReadsLimiter guards decodeLimiterEntry, which depends on
+// how the limiter middleware lays out its entries in storage
+func TestRateLimitUsageReadsLimiter(t *testing.T) {
+	const (
+		route  = "GET /limited"
+		caller = "ip:test"
+	)
+	limit := RateLimit{Max: 3, Window: time.Minute}
+
+	store := newLimitStore()
+	store.register(route, limit)
+	store.register("GET /idle", RateLimit{Max: 5, Window: time.Minute})
+
+	app := fiber.New()
+	app.Get("/limited", limiter.New(limiter.Config{
+		Max:        limit.Max,
+		Expiration: limit.Window,
+		Storage:    store,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return rateLimitKey(route, caller)
+		},
+	}), func(c *fiber.Ctx) error {
+		return c.SendStatus(204)
+	})
+
+	send := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/limited", nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	start := time.Now()
+	send(2)
+
+	usage := store.rateLimitUsage(caller, time.Now())
+	if len(usage) != 2 {
+		t.Fatalf("got %d routes, want 2", len(usage))
+	}
+
+	idle, limited := usage[0], usage[1]
+	if idle.Used != 0 || idle.Remaining != 5 || idle.ResetAt != nil {
+		t.Errorf("idle route: got %+v, want nothing used and no open window", idle)
+	}
+	if limited.Used != 2 || limited.Remaining != 1 || limited.Limit != 3 {
+		t.Errorf("limited route: got used %d, remaining %d, limit %d, want 2, 1, 3", limited.Used, limited.Remaining, limited.Limit)
+	}
+	if limited.ResetAt == nil {
+		t.Fatal("limited route has no reset time")
+	}
+	// The limiter counts in whole seconds
+	earliest := start.Add(limit.Window).Add(-2 * time.Second)
+	latest := time.Now().Add(limit.Window).Add(2 * time.Second)
+	if limited.ResetAt.Before(earliest) || limited.ResetAt.After(latest) {
+		t.Errorf("reset at %s, want about a window after the first request, between %s and %s", limited.ResetAt, earliest, latest)
+	}
+
+	send(2)
+	if got := store.rateLimitUsage(caller, time.Now())[1]; got.Remaining != 0 {
+		t.Errorf("after exceeding the limit got remaining %d, want 0", got.Remaining)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/swagger"
 
 	_ "fiber-go-swagger/docs" // Import generated docs
 )
@@ -22,32 +24,109 @@ import (
 // @BasePath /api/v1
 // @schemes http https
 func main() {
-	app := fiber.New()
+	if len(os.Args) > 1 && os.Args[1] == "fuzz-api" {
+		os.Exit(runFuzzAPI(os.Args[2:]))
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app := fiber.New(fiber.Config{
+		CaseSensitive: cfg.CaseSensitive,
+		StrictRouting: cfg.StrictRouting,
+		JSONEncoder:   encodeMaskedJSON,
+	})
 
-	// Enable CORS
-	app.Use(cors.New())
+	// Expose the running version on every response
+	app.Use(versionMiddleware)
+
+	// Serve generated users that are reset periodically
+	if cfg.Sandbox {
+		sb := startSandbox(users, cfg.SandboxUsers, cfg.SandboxResetInterval)
+		app.Use(sb.middleware)
+	}
+
+	// Record recent requests so they can be inspected and replayed
+	var recorder *requestRecorder
+	if cfg.IsDevelopment() {
+		recorder = newRequestRecorder(cfg.RecorderSize)
+		app.Use(recorder.middleware)
+	}
+
+	// Apply the CORS policy of each route group
+	app.Use("/version", corsHandler(cfg.CORS[corsPublic]))
+	app.Use("/swagger", corsHandler(cfg.CORS[corsPublic]))
+	app.Use("/api/v1", corsHandler(cfg.CORS[corsAPI]))
+	app.Use("/admin", corsHandler(cfg.CORS[corsAdmin]))
+
+	// Redirect /users/ to /users so both spellings resolve to the same route,
+	// after CORS so redirects and preflights carry its headers
+	if cfg.RedirectTrailingSlash {
+		app.Use(trailingSlashRedirect)
+	}
+
+	// Resolve the API key of the caller, if any
+	app.Use(authenticate(cfg))
+
+	// Version route
+	registerRoutes(app, cfg, []Route{
+		{Method: fiber.MethodGet, Path: "/version", Handler: getVersion, Cache: time.Minute},
+	})
+
+	// Admin routes
+	admin := app.Group("/admin")
+	registerRoutes(admin, cfg, []Route{
+		{
+			Method: fiber.MethodGet, Path: "/diagnostics", Handler: getDiagnostics(cfg),
+			Timeout: 10 * time.Second, RateLimit: RateLimit{Max: 10, Window: time.Minute}, Scopes: []string{adminScope},
+		},
+	})
+
+	// Development routes
+	if recorder != nil {
+		dev := app.Group(devPrefix)
+		registerRoutes(dev, cfg, []Route{
+			{Method: fiber.MethodGet, Path: "/requests", Handler: recorder.getRecordedRequests, Scopes: []string{adminScope}},
+			{Method: fiber.MethodPost, Path: "/requests/:id/replay", Handler: recorder.replayRequest(app), Scopes: []string{adminScope}},
+		})
+	}
 
 	// Swagger route
-	app.Get("/swagger/*", swagger.HandlerDefault)
+	app.Get("/swagger/*", swaggerUI())
 
 	// API routes
 	api := app.Group("/api/v1")
 
 	// User routes
-	api.Get("/users", getUsers)
-	api.Get("/users/:id", getUserByID)
-	api.Post("/users", createUser)
-	api.Put("/users/:id", updateUser)
-	api.Delete("/users/:id", deleteUser)
+	userLimit := RateLimit{Max: 100, Window: time.Minute}
+	registerRoutes(api, cfg, []Route{
+		{Method: fiber.MethodGet, Path: "/users", Handler: getUsers, RateLimit: userLimit, Quota: true},
+		{Method: fiber.MethodGet, Path: "/users/:id", Handler: getUserByID, RateLimit: userLimit, Quota: true},
+		{Method: fiber.MethodPost, Path: "/users", Handler: createUser, RateLimit: userLimit, Quota: true},
+		{Method: fiber.MethodPut, Path: "/users/:id", Handler: updateUser, RateLimit: userLimit, Quota: true},
+		{Method: fiber.MethodDelete, Path: "/users/:id", Handler: deleteUser, RateLimit: userLimit, Quota: true},
+	})
+
+	// Caller routes
+	registerRoutes(api, cfg, []Route{
+		{Method: fiber.MethodGet, Path: "/me/usage", Handler: getUsage(cfg), RateLimit: RateLimit{Max: 60, Window: time.Minute}},
+	})
+
+	if cfg.TLSCertFile != "" {
+		log.Fatal(app.ListenTLS(cfg.Addr(), cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
 
-	log.Fatal(app.Listen(":3000"))
+	log.Fatal(app.Listen(cfg.Addr()))
 }
 
 // User represents a user in the system
 type User struct {
-	ID    int    `json:"id" example:"1"`
+	ID    int    `json:"id" example:"1" mask:"owner"`
 	Name  string `json:"name" example:"John Doe"`
-	Email string `json:"email" example:"john@example.com"`
+	Email string `json:"email" example:"john@example.com" mask:"redact:self,admin"`
 	Age   int    `json:"age" example:"30"`
 }
 
@@ -72,23 +151,40 @@ type SuccessResponse struct {
 
 // getUsers godoc
 // @Summary Get all users
-// @Description Get a list of all users
+// @Description Get a list of users, optionally filtered
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Number of items per page" default(10)
+// @Param limit query int false "Number of items per page" default(10) maximum(100)
+// @Param filter query string false "RSQL/FIQL filter on id, name and age, e.g. age>=18;name==John*"
 // @Success 200 {array} User
+// @Header 200 {string} Link "Links to the next, prev, first and last pages (RFC 5988)"
+// @Header 200 {integer} X-Total-Count "Total number of users"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /users [get]
 func getUsers(c *fiber.Ctx) error {
-	// Mock data
-	users := []User{
-		{ID: 1, Name: "John Doe", Email: "john@example.com", Age: 30},
-		{ID: 2, Name: "Jane Smith", Email: "jane@example.com", Age: 25},
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(400).JSON(ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
 	}
 
-	return c.JSON(users)
+	match, err := parseUserFilter(c.Query("filter"))
+	if err != nil {
+		return c.Status(400).JSON(ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+	}
+
+	found := users.find(match)
+	setPaginationHeaders(c, page, len(found))
+
+	return respond(c, 200, paginate(found, page))
 }
 
 // getUserByID godoc
@@ -103,24 +199,17 @@ func getUsers(c *fiber.Ctx) error {
 // @Failure 404 {object} ErrorResponse
 // @Router /users/{id} [get]
 func getUserByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	// Mock data - in real app, fetch from database
-	user := User{
-		ID:    1,
-		Name:  "John Doe",
-		Email: "john@example.com",
-		Age:   30,
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return invalidUserID(c)
 	}
 
-	if id == "1" {
-		return c.JSON(user)
+	user, ok := users.get(id)
+	if !ok {
+		return userNotFound(c)
 	}
 
-	return c.Status(404).JSON(ErrorResponse{
-		Error:   "Not Found",
-		Message: "User not found",
-	})
+	return respond(c, 200, user)
 }
 
 // createUser godoc
@@ -133,6 +222,7 @@ func getUserByID(c *fiber.Ctx) error {
 // @Success 201 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /users [post]
 func createUser(c *fiber.Ctx) error {
 	var req CreateUserRequest
@@ -144,18 +234,17 @@ func createUser(c *fiber.Ctx) error {
 		})
 	}
 
-	// In a real app, save to database and return the created user
+	user, err := users.create(req)
+	if err != nil {
+		return storeFull(c)
+	}
+
 	response := SuccessResponse{
 		Message: "User created successfully",
-		Data: User{
-			ID:    3,
-			Name:  req.Name,
-			Email: req.Email,
-			Age:   req.Age,
-		},
+		Data:    user,
 	}
 
-	return c.Status(201).JSON(response)
+	return respond(c, 201, response)
 }
 
 // updateUser godoc
@@ -169,9 +258,13 @@ func createUser(c *fiber.Ctx) error {
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /users/{id} [put]
 func updateUser(c *fiber.Ctx) error {
-	id := c.Params("id")
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return invalidUserID(c)
+	}
 
 	log.Println("update user id:", id)
 
@@ -184,18 +277,20 @@ func updateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	// Mock update - in real app, update in database
+	user, err := users.update(id, req)
+	if errors.Is(err, errStoreFull) {
+		return storeFull(c)
+	}
+	if err != nil {
+		return userNotFound(c)
+	}
+
 	response := SuccessResponse{
 		Message: "User updated successfully",
-		Data: User{
-			ID:    1, // Would be parsed from id param
-			Name:  req.Name,
-			Email: req.Email,
-			Age:   req.Age,
-		},
+		Data:    user,
 	}
 
-	return c.JSON(response)
+	return respond(c, 200, response)
 }
 
 // deleteUser godoc
@@ -210,14 +305,44 @@ func updateUser(c *fiber.Ctx) error {
 // @Failure 404 {object} ErrorResponse
 // @Router /users/{id} [delete]
 func deleteUser(c *fiber.Ctx) error {
-	id := c.Params("id")
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return invalidUserID(c)
+	}
 
 	log.Println("delete user id:", id)
 
-	// Mock deletion - in real app, delete from database
+	if !users.delete(id) {
+		return userNotFound(c)
+	}
+
 	response := SuccessResponse{
 		Message: "User deleted successfully",
 	}
 
 	return c.JSON(response)
 }
+
+// invalidUserID responds with 400 for a malformed user ID
+func invalidUserID(c *fiber.Ctx) error {
+	return c.Status(400).JSON(ErrorResponse{
+		Error:   "Bad Request",
+		Message: "Invalid user ID",
+	})
+}
+
+// userNotFound responds with 404 for a missing user
+func userNotFound(c *fiber.Ctx) error {
+	return c.Status(404).JSON(ErrorResponse{
+		Error:   "Not Found",
+		Message: "User not found",
+	})
+}
+
+// storeFull responds with 503 when the user store cannot take more users
+func storeFull(c *fiber.Ctx) error {
+	return c.Status(503).JSON(ErrorResponse{
+		Error:   "Service Unavailable",
+		Message: "User store is full",
+	})
+}
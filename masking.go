@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Field masking is declared with the mask struct tag:
+//
+//	ID    int    `mask:"owner"`
+//	Email string `mask:"redact:self,admin"`
+//	Phone string `json:",omitempty" mask:"hide:admin"`
+//
+// "owner" marks the field identifying the owner of the value. "redact" and
+// "hide" list who may see the field: "self" is the owner, anything else is a
+// scope the caller must hold. Everyone else gets redactedValue for redacted
+// strings, and the zero value otherwise, so hidden fields should be omitempty.
+const (
+	maskTag       = "mask"
+	maskOwner     = "owner"
+	maskSelf      = "self"
+	redactedValue = "***"
+)
+
+// respond sends v as JSON with the given status, revealing the masked fields
+// the caller may see
+func respond(c *fiber.Ctx, status int, v interface{}) error {
+	data, err := json.Marshal(masked(v, currentPrincipal(c)))
+	if err != nil {
+		return err
+	}
+
+	c.Status(status).Type("json")
+	return c.Send(data)
+}
+
+// encodeMaskedJSON is the JSON encoder of the app. It masks values as seen by
+// an anonymous caller, so responses that do not go through respond cannot
+// leak masked fields.
+func encodeMaskedJSON(v interface{}) ([]byte, error) {
+	return json.Marshal(masked(v, nil))
+}
+
+// masked returns a copy of v with the fields the principal may not see masked
+func masked(v interface{}, p *Principal) interface{} {
+	out := maskFields(reflect.ValueOf(v), p)
+	if !out.IsValid() {
+		return v
+	}
+
+	return out.Interface()
+}
+
+// maskFields returns a copy of v with every masked field the principal may not
+// see redacted or zeroed. A nil principal is an anonymous caller.
+func maskFields(v reflect.Value, p *Principal) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(maskFields(v.Elem(), p))
+		return out
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(maskFields(v.Elem(), p))
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v
+		}
+		var out reflect.Value
+		if v.Kind() == reflect.Slice {
+			out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		} else {
+			out = reflect.New(v.Type()).Elem()
+		}
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(maskFields(v.Index(i), p))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), maskFields(iter.Value(), p))
+		}
+		return out
+	case reflect.Struct:
+		return maskStruct(v, p)
+	default:
+		return v
+	}
+}
+
+// maskStruct applies the mask tags of a struct's fields
+func maskStruct(v reflect.Value, p *Principal) reflect.Value {
+	t := v.Type()
+	out := reflect.New(t).Elem()
+	out.Set(v)
+
+	owner := ""
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get(maskTag) == maskOwner {
+			owner = fmt.Sprint(v.Field(i).Interface())
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := out.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		action, audience, ok := strings.Cut(t.Field(i).Tag.Get(maskTag), ":")
+		if !ok {
+			field.Set(maskFields(field, p))
+			continue
+		}
+		if canSee(p, owner, strings.Split(audience, ",")) {
+			continue
+		}
+
+		if action == "redact" && field.Kind() == reflect.String && field.Len() > 0 {
+			field.SetString(redactedValue)
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return out
+}
+
+// canSee reports whether the principal belongs to the audience of a field
+func canSee(p *Principal, owner string, audience []string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, who := range audience {
+		who = strings.TrimSpace(who)
+		if who == maskSelf && owner != "" && p.Subject == owner || who != maskSelf && p.HasScope(who) {
+			return true
+		}
+	}
+
+	return false
+}
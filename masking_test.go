@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	maskedUser = User{ID: 2, Name: "Jane Smith", Email: "jane@example.com", Age: 25}
+
+	anonymousCaller *Principal
+	selfCaller      = &Principal{Subject: "2"}
+	otherCaller     = &Principal{Subject: "1"}
+	adminCaller     = &Principal{Subject: "admin", Scopes: []string{adminScope}}
+)
+
+func TestMaskFieldsUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller *Principal
+		email  string
+	}{
+		{"anonymous", anonymousCaller, redactedValue},
+		{"self", selfCaller, "jane@example.com"},
+		{"other user", otherCaller, redactedValue},
+		{"admin", adminCaller, "jane@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := masked(maskedUser, tt.caller).(User)
+			want := maskedUser
+			want.Email = tt.email
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMaskFieldsNested(t *testing.T) {
+	v := SuccessResponse{Data: []User{maskedUser, {ID: 1, Email: "john@example.com"}}}
+
+	got := masked(v, selfCaller).(SuccessResponse).Data.([]User)
+	if got[0].Email != "jane@example.com" || got[1].Email != redactedValue {
+		t.Errorf("got emails %q and %q, want the caller's own email only", got[0].Email, got[1].Email)
+	}
+	if v.Data.([]User)[1].Email != "john@example.com" {
+		t.Error("masking modified the original value")
+	}
+}
+
+func TestMaskFieldsHide(t *testing.T) {
+	type account struct {
+		Owner  string `json:"owner" mask:"owner"`
+		Phone  string `json:"phone,omitempty" mask:"hide:admin"`
+		Secret int    `json:"secret,omitempty" mask:"redact:self"`
+	}
+	v := account{Owner: "2", Phone: "555-0100", Secret: 42}
+
+	if got := masked(v, selfCaller).(account); got.Phone != "" || got.Secret != 42 {
+		t.Errorf("self: got %+v, want phone hidden and secret kept", got)
+	}
+	if got := masked(v, adminCaller).(account); got.Phone != "555-0100" || got.Secret != 0 {
+		t.Errorf("admin: got %+v, want phone kept and non-string secret zeroed", got)
+	}
+}
+
+func TestEncodeMaskedJSONIsAnonymous(t *testing.T) {
+	data, err := encodeMaskedJSON(maskedUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got User
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != redactedValue {
+		t.Errorf("got email %q, want it redacted", got.Email)
+	}
+}
+
+func TestRespondUsesPrincipal(t *testing.T) {
+	app := fiber.New(fiber.Config{JSONEncoder: encodeMaskedJSON})
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals(principalKey, selfCaller)
+		return respond(c, 200, maskedUser)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	var got User
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", body, err)
+	}
+	if got.Email != "jane@example.com" {
+		t.Errorf("got email %q, want it visible to its owner", got.Email)
+	}
+	if ct := resp.Header.Get(fiber.HeaderContentType); ct != fiber.MIMEApplicationJSON {
+		t.Errorf("got Content-Type %q, want %q", ct, fiber.MIMEApplicationJSON)
+	}
+}
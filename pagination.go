@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+
+	totalCountHeader = "X-Total-Count"
+)
+
+// pageRequest is the page of a list requested through the page and limit query params
+type pageRequest struct {
+	Page  int
+	Limit int
+}
+
+// parsePageRequest reads the page and limit query params. Limits above
+// maxPageLimit are capped rather than rejected.
+func parsePageRequest(c *fiber.Ctx) (pageRequest, error) {
+	p := pageRequest{Page: 1, Limit: defaultPageLimit}
+
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("page must be a positive integer")
+		}
+		p.Page = n
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = min(n, maxPageLimit)
+	}
+
+	return p, nil
+}
+
+// lastPage returns the number of the last page for total items, at least 1
+func (p pageRequest) lastPage(total int) int {
+	return max(1, (total+p.Limit-1)/p.Limit)
+}
+
+// paginate returns the items on the requested page
+func paginate[T any](items []T, p pageRequest) []T {
+	start := (p.Page - 1) * p.Limit
+	if start >= len(items) || start < 0 {
+		return []T{}
+	}
+
+	return items[start:min(start+p.Limit, len(items))]
+}
+
+// setPaginationHeaders emits the total count and RFC 5988 Link headers pointing
+// to the next, previous, first and last pages. The links keep every other
+// query param of the current request.
+func setPaginationHeaders(c *fiber.Ctx, p pageRequest, total int) {
+	last := p.lastPage(total)
+
+	pageURL := func(page int) string {
+		args := fiber.AcquireArgs()
+		defer fiber.ReleaseArgs(args)
+
+		c.Request().URI().QueryArgs().CopyTo(args)
+		args.Set("page", strconv.Itoa(page))
+		args.Set("limit", strconv.Itoa(p.Limit))
+
+		return c.BaseURL() + c.Path() + "?" + args.String()
+	}
+
+	var links []string
+	if p.Page < last {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(p.Page+1)))
+	}
+	if p.Page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(p.Page-1, last))))
+	}
+	links = append(links,
+		fmt.Sprintf(`<%s>; rel="first"`, pageURL(1)),
+		fmt.Sprintf(`<%s>; rel="last"`, pageURL(last)),
+	)
+
+	c.Set(totalCountHeader, strconv.Itoa(total))
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
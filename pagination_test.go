@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// withCtx runs fn with a context for a GET request to uri
+func withCtx(t *testing.T, uri string, fn func(c *fiber.Ctx)) {
+	t.Helper()
+
+	app := fiber.New()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI(uri)
+	fctx.Request.Header.SetHost("example.com")
+
+	c := app.AcquireCtx(fctx)
+	defer app.ReleaseCtx(c)
+	fn(c)
+}
+
+func TestParsePageRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    pageRequest
+		wantErr string
+	}{
+		{"", pageRequest{Page: 1, Limit: defaultPageLimit}, ""},
+		{"page=3&limit=5", pageRequest{Page: 3, Limit: 5}, ""},
+		{"limit=1000", pageRequest{Page: 1, Limit: maxPageLimit}, ""},
+		{"page=0", pageRequest{}, "page must be a positive integer"},
+		{"page=abc", pageRequest{}, "page must be a positive integer"},
+		{"limit=-1", pageRequest{}, "limit must be a positive integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			withCtx(t, "/users?"+tt.query, func(c *fiber.Ctx) {
+				got, err := parsePageRequest(c)
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("got error %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("got %+v, want %+v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		page pageRequest
+		want []int
+	}{
+		{pageRequest{Page: 1, Limit: 2}, []int{1, 2}},
+		{pageRequest{Page: 3, Limit: 2}, []int{5}},
+		{pageRequest{Page: 4, Limit: 2}, []int{}},
+		{pageRequest{Page: 1, Limit: 10}, []int{1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		if got := paginate(items, tt.page); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("paginate(%+v) = %v, want %v", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		page  pageRequest
+		total int
+		want  []string
+	}{
+		{
+			name: "middle page keeps other params", uri: "/users?filter=age%3E18&page=2&limit=2",
+			page: pageRequest{Page: 2, Limit: 2}, total: 5,
+			want: []string{
+				`<http://example.com/users?filter=age%3E18&page=3&limit=2>; rel="next"`,
+				`<http://example.com/users?filter=age%3E18&page=1&limit=2>; rel="prev"`,
+				`<http://example.com/users?filter=age%3E18&page=1&limit=2>; rel="first"`,
+				`<http://example.com/users?filter=age%3E18&page=3&limit=2>; rel="last"`,
+			},
+		},
+		{
+			name: "single page", uri: "/users",
+			page: pageRequest{Page: 1, Limit: 10}, total: 0,
+			want: []string{
+				`<http://example.com/users?page=1&limit=10>; rel="first"`,
+				`<http://example.com/users?page=1&limit=10>; rel="last"`,
+			},
+		},
+		{
+			name: "past the end points prev at the last page", uri: "/users?page=9&limit=2",
+			page: pageRequest{Page: 9, Limit: 2}, total: 3,
+			want: []string{
+				`<http://example.com/users?page=2&limit=2>; rel="prev"`,
+				`<http://example.com/users?page=1&limit=2>; rel="first"`,
+				`<http://example.com/users?page=2&limit=2>; rel="last"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCtx(t, tt.uri, func(c *fiber.Ctx) {
+				setPaginationHeaders(c, tt.page, tt.total)
+
+				if got := c.GetRespHeader(fiber.HeaderLink); got != strings.Join(tt.want, ", ") {
+					t.Errorf("got Link\n%s\nwant\n%s", got, strings.Join(tt.want, ", "))
+				}
+				if got := c.GetRespHeader(totalCountHeader); got != fmt.Sprint(tt.total) {
+					t.Errorf("got %s %q, want %d", totalCountHeader, got, tt.total)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const (
+	// maxRecordedBody caps the request body kept per recorded request
+	maxRecordedBody = 64 << 10
+	// devPrefix holds the recorder routes, which are not recorded themselves
+	devPrefix = "/dev"
+)
+
+// RecordedRequest is a request captured by the dev-mode recorder
+type RecordedRequest struct {
+	ID        int               `json:"id" example:"42"`
+	Time      time.Time         `json:"time" example:"2024-01-01T00:00:00Z"`
+	Method    string            `json:"method" example:"POST"`
+	URL       string            `json:"url" example:"/api/v1/users?page=1"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body" example:"{\"name\":\"John Doe\"}"`
+	Truncated bool              `json:"truncated" example:"false"`
+	Status    int               `json:"status" example:"201"`
+}
+
+// ReplayResponse is the response of the app to a replayed request
+type ReplayResponse struct {
+	Request RecordedRequest   `json:"request"`
+	Status  int               `json:"status" example:"201"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body" example:"{\"message\":\"User created successfully\"}"`
+}
+
+// requestRecorder keeps the most recent requests in a ring buffer
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+	next     int
+	lastID   int
+}
+
+// newRequestRecorder returns a recorder keeping the last size requests
+func newRequestRecorder(size int) *requestRecorder {
+	return &requestRecorder{requests: make([]RecordedRequest, 0, size)}
+}
+
+// middleware records every request passing through it, along with the
+// status it was answered with
+func (r *requestRecorder) middleware(c *fiber.Ctx) error {
+	if isDevPath(c.Path()) {
+		return c.Next()
+	}
+
+	rec := RecordedRequest{
+		Time:    time.Now().UTC(),
+		Method:  utils.CopyString(c.Method()),
+		URL:     utils.CopyString(c.OriginalURL()),
+		Headers: make(map[string]string),
+	}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		rec.Headers[string(key)] = string(value)
+	})
+	body := c.Body()
+	if len(body) > maxRecordedBody {
+		body, rec.Truncated = body[:maxRecordedBody], true
+	}
+	rec.Body = string(body)
+
+	err := c.Next()
+
+	rec.Status = c.Response().StatusCode()
+	if err != nil {
+		if e, ok := err.(*fiber.Error); ok {
+			rec.Status = e.Code
+		}
+	}
+	r.add(rec)
+
+	return err
+}
+
+// isDevPath reports whether path is under devPrefix. Routing may be case
+// insensitive, so the prefix is too.
+func isDevPath(path string) bool {
+	prefix := devPrefix + "/"
+	return len(path) >= len(prefix) && strings.EqualFold(path[:len(prefix)], prefix)
+}
+
+// sensitiveHeaders are shown as redactedValue when recorded requests are listed
+var sensitiveHeaders = []string{fiber.HeaderAuthorization, fiber.HeaderCookie, fiber.HeaderProxyAuthorization}
+
+// redacted returns a copy of rec whose credentials are hidden. The recorder keeps
+// them so replays authenticate as the original caller.
+func (rec RecordedRequest) redacted() RecordedRequest {
+	headers := make(map[string]string, len(rec.Headers))
+	for key, value := range rec.Headers {
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(key, sensitive) {
+				value = redactedValue
+				break
+			}
+		}
+		headers[key] = value
+	}
+	rec.Headers = headers
+
+	return rec
+}
+
+// add stores rec, evicting the oldest request once the buffer is full
+func (r *requestRecorder) add(rec RecordedRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	rec.ID = r.lastID
+
+	if len(r.requests) < cap(r.requests) {
+		r.requests = append(r.requests, rec)
+		return
+	}
+	r.requests[r.next] = rec
+	r.next = (r.next + 1) % len(r.requests)
+}
+
+// list returns the recorded requests, newest first
+func (r *requestRecorder) list() []RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.requests)
+	out := make([]RecordedRequest, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, r.requests[(r.next+n-1-i)%n])
+	}
+
+	return out
+}
+
+// get returns the recorded request with the given id
+func (r *requestRecorder) get(id int) (RecordedRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rec := range r.requests {
+		if rec.ID == id {
+			return rec, true
+		}
+	}
+
+	return RecordedRequest{}, false
+}
+
+// getRecordedRequests lists the recorded requests, newest first, with their credentials redacted
+func (r *requestRecorder) getRecordedRequests(c *fiber.Ctx) error {
+	recs := r.list()
+	for i := range recs {
+		recs[i] = recs[i].redacted()
+	}
+
+	return c.JSON(recs)
+}
+
+// replayRequest returns a handler sending a recorded request through app again
+func (r *requestRecorder) replayRequest(app *fiber.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(400).JSON(ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid request ID",
+			})
+		}
+
+		rec, ok := r.get(id)
+		if !ok {
+			return c.Status(404).JSON(ErrorResponse{
+				Error:   "Not Found",
+				Message: "Recorded request not found",
+			})
+		}
+
+		if rec.Truncated {
+			return c.Status(400).JSON(ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Recorded request body was truncated and cannot be replayed",
+			})
+		}
+
+		req, err := http.NewRequest(rec.Method, rec.URL, strings.NewReader(rec.Body))
+		if err != nil {
+			return c.Status(400).JSON(ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Recorded request cannot be replayed: " + err.Error(),
+			})
+		}
+		if isDevPath(req.URL.Path) {
+			return c.Status(400).JSON(ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Requests to the recorder routes cannot be replayed",
+			})
+		}
+		req.Host = rec.Headers[fiber.HeaderHost]
+		for key, value := range rec.Headers {
+			if key != fiber.HeaderContentLength {
+				req.Header.Set(key, value)
+			}
+		}
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(resp.Body); err != nil {
+			return err
+		}
+
+		headers := make(map[string]string, len(resp.Header))
+		for key := range resp.Header {
+			headers[key] = resp.Header.Get(key)
+		}
+
+		return c.JSON(ReplayResponse{
+			Request: rec.redacted(),
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    body.String(),
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newRecorderApp returns an app recording its requests, with the recorder
+// routes mounted the way main does, minus the scopes
+func newRecorderApp() (*fiber.App, *requestRecorder) {
+	app := fiber.New()
+	recorder := newRequestRecorder(10)
+	app.Use(recorder.middleware)
+
+	dev := app.Group(devPrefix)
+	dev.Get("/requests", recorder.getRecordedRequests)
+	dev.Post("/requests/:id/replay", recorder.replayRequest(app))
+	app.Get("/hello", func(c *fiber.Ctx) error {
+		return c.SendString("hello")
+	})
+
+	return app, recorder
+}
+
+func TestRecorderSkipsDevRoutes(t *testing.T) {
+	app, recorder := newRecorderApp()
+
+	for _, path := range []string{"/dev/requests", "/DEV/requests", "/Dev/requests/1/replay"} {
+		method := fiber.MethodGet
+		if strings.HasSuffix(path, "/replay") {
+			method = fiber.MethodPost
+		}
+		if _, err := app.Test(httptest.NewRequest(method, path, nil)); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+
+	if recs := recorder.list(); len(recs) != 0 {
+		t.Errorf("recorded %d requests to the recorder routes, want none", len(recs))
+	}
+}
+
+func TestReplayRefusesDevRoutes(t *testing.T) {
+	app, recorder := newRecorderApp()
+	recorder.add(RecordedRequest{Method: fiber.MethodPost, URL: "/DEV/requests/1/replay", Headers: map[string]string{}})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/dev/requests/1/replay", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 400 {
+		t.Errorf("got status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRecordedCredentialsAreRedacted(t *testing.T) {
+	app, _ := newRecorderApp()
+
+	req := httptest.NewRequest(fiber.MethodGet, "/hello", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer secret-token")
+	req.Header.Set(fiber.HeaderCookie, "session=secret")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/dev/requests", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	var recs []RecordedRequest
+	if err := json.Unmarshal(body, &recs); err != nil {
+		t.Fatalf("invalid JSON %q: %v", body, err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %d recorded requests, want 1", len(recs))
+	}
+	for _, header := range []string{fiber.HeaderAuthorization, fiber.HeaderCookie} {
+		if got := recs[0].Headers[header]; got != redactedValue {
+			t.Errorf("listed %s %q, want %q", header, got, redactedValue)
+		}
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/dev/requests/1/replay", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "secret") {
+		t.Errorf("replay response leaks credentials: %s", body)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("replay status %d, want 200: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cache"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/gofiber/fiber/v2/middleware/timeout"
+)
+
+// Route declares an endpoint together with the policies applied to it.
+// Zero values disable the corresponding policy.
+type Route struct {
+	Method  string
+	Path    string
+	Handler fiber.Handler
+
+	// Timeout cancels the handler's user context after this long. It only
+	// bounds handlers that honour c.UserContext(), such as the diagnostics.
+	Timeout time.Duration
+	// RateLimit caps requests per caller on this route
+	RateLimit RateLimit
+	// Scopes must all be granted to the caller
+	Scopes []string
+	// Cache serves successful GET responses from memory for this long,
+	// separately for every principal since responses are masked per caller
+	Cache time.Duration
+	// Quota counts requests against the caller's monthly quota
+	Quota bool
+}
+
+// RateLimit allows Max requests per Window
+type RateLimit struct {
+	Max    int
+	Window time.Duration
+}
+
+// registerRoutes mounts the routes on router, wiring the middleware each one declares
+func registerRoutes(router fiber.Router, cfg Config, routes []Route) {
+	prefix := ""
+	if group, ok := router.(*fiber.Group); ok {
+		prefix = group.Prefix
+	}
+
+	for _, route := range routes {
+		name := route.Method + " " + prefix + route.Path
+		router.Add(route.Method, route.Path, routeHandlers(cfg, name, route)...)
+	}
+}
+
+// routeHandlers builds the middleware chain for a route, ending with its handler.
+// name identifies the route in usage reports.
+func routeHandlers(cfg Config, name string, route Route) []fiber.Handler {
+	var handlers []fiber.Handler
+
+	if route.RateLimit.Max > 0 {
+		limits.register(name, route.RateLimit)
+		handlers = append(handlers, limiter.New(limiter.Config{
+			Max:        route.RateLimit.Max,
+			Expiration: route.RateLimit.Window,
+			Storage:    limits,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return rateLimitKey(name, callerKey(c))
+			},
+			LimitReached: func(c *fiber.Ctx) error {
+				return c.Status(429).JSON(ErrorResponse{
+					Error:   "Too Many Requests",
+					Message: "Rate limit exceeded",
+				})
+			},
+		}))
+	}
+
+	if len(route.Scopes) > 0 {
+		handlers = append(handlers, requireScopes(cfg, route.Scopes...))
+	}
+
+	if route.Quota && cfg.MonthlyQuota > 0 {
+		handlers = append(handlers, limits.quota(cfg.MonthlyQuota))
+	}
+
+	if route.Cache > 0 {
+		handlers = append(handlers, cache.New(cache.Config{
+			Expiration:   route.Cache,
+			CacheControl: true,
+			KeyGenerator: cacheKey,
+		}))
+	}
+
+	handler := route.Handler
+	if route.Timeout > 0 {
+		handler = timeout.NewWithContext(handler, route.Timeout)
+	}
+
+	return append(handlers, handler)
+}
+
+// callerKey identifies the caller for rate limiting: the authenticated subject,
+// falling back to the client IP
+func callerKey(c *fiber.Ctx) string {
+	if p := currentPrincipal(c); p != nil {
+		return "subject:" + p.Subject
+	}
+
+	return "ip:" + c.IP()
+}
+
+// cacheKey identifies a cached response by URL and principal, so a response
+// masked for one caller is never served to another. Anonymous callers share
+// their entries.
+func cacheKey(c *fiber.Ctx) string {
+	caller := ""
+	if p := currentPrincipal(c); p != nil {
+		caller = p.Subject + ":" + strings.Join(p.Scopes, " ")
+	}
+
+	return caller + "|" + c.OriginalURL()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// trailingSlashRedirect permanently redirects paths with a trailing slash to
+// their canonical form without it, keeping the method and query string.
+// Leading slashes are collapsed so "//evil.com/" cannot turn into a
+// protocol-relative redirect to another host.
+func trailingSlashRedirect(c *fiber.Ctx) error {
+	path := c.Path()
+	if len(path) <= 1 || !strings.HasSuffix(path, "/") {
+		return c.Next()
+	}
+
+	location := "/" + strings.TrimLeft(strings.TrimRight(path, "/"), `/\`)
+	if query := c.Request().URI().QueryString(); len(query) > 0 {
+		location += "?" + string(query)
+	}
+
+	return c.Redirect(location, fiber.StatusPermanentRedirect)
+}
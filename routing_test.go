@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func TestTrailingSlashRedirect(t *testing.T) {
+	tests := []struct {
+		path     string
+		status   int
+		location string
+	}{
+		{"//evil.com/", fiber.StatusPermanentRedirect, "/evil.com"},
+		{`/\evil.com/`, fiber.StatusPermanentRedirect, "/evil.com"},
+		{"///evil.com//", fiber.StatusPermanentRedirect, "/evil.com"},
+		{"/users/?a=1", fiber.StatusPermanentRedirect, "/users?a=1"},
+		{"/users//", fiber.StatusPermanentRedirect, "/users"},
+		{"/", fiber.StatusOK, ""},
+		{"/users", fiber.StatusOK, ""},
+	}
+
+	app := fiber.New()
+	app.Use(trailingSlashRedirect)
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	handler := app.Handler()
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// Build the request by hand, net/http would clean the path
+			fctx := &fasthttp.RequestCtx{}
+			fctx.Request.Header.SetMethod(fiber.MethodGet)
+			fctx.Request.Header.SetHost("example.com")
+			fctx.Request.URI().DisablePathNormalizing = true
+			fctx.Request.SetRequestURI(tt.path)
+
+			handler(fctx)
+
+			if got := fctx.Response.StatusCode(); got != tt.status {
+				t.Fatalf("got status %d, want %d", got, tt.status)
+			}
+			location := string(fctx.Response.Header.Peek(fiber.HeaderLocation))
+			if location != tt.location {
+				t.Errorf("got Location %q, want %q", location, tt.location)
+			}
+			if len(location) > 1 && (location[1] == '/' || location[1] == '\\') {
+				t.Errorf("Location %q points to another host", location)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// sandboxResetHeader tells sandbox clients when their changes will be discarded
+	sandboxResetHeader = "X-Sandbox-Reset-At"
+	// sandboxMaxNewUsers caps how many users clients can add between resets
+	sandboxMaxNewUsers = 1000
+)
+
+var (
+	fakeFirstNames = []string{
+		"Ada", "Alan", "Barbara", "Claude", "Donald", "Edsger", "Frances", "Grace", "Guido", "Hedy",
+		"Ivan", "Jean", "Ken", "Linus", "Margaret", "Niklaus", "Radia", "Rob", "Sophie", "Tim",
+	}
+	fakeLastNames = []string{
+		"Allen", "Bartik", "Berners-Lee", "Dijkstra", "Hamilton", "Hopper", "Kernighan", "Knuth", "Lamarr", "Liskov",
+		"Lovelace", "Perlman", "Pike", "Ritchie", "Shannon", "Sutherland", "Thompson", "Torvalds", "Turing", "Wirth",
+	}
+	fakeDomains = []string{"example.com", "example.net", "example.org"}
+)
+
+// fakeUsers generates n plausible users with IDs 1..n
+func fakeUsers(rnd *rand.Rand, n int) []User {
+	out := make([]User, n)
+	for i := range out {
+		first := fakeFirstNames[rnd.Intn(len(fakeFirstNames))]
+		last := fakeLastNames[rnd.Intn(len(fakeLastNames))]
+		out[i] = User{
+			ID:    i + 1,
+			Name:  first + " " + last,
+			Email: fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), i+1, fakeDomains[rnd.Intn(len(fakeDomains))]),
+			Age:   18 + rnd.Intn(63),
+		}
+	}
+
+	return out
+}
+
+// sandbox periodically resets a store to freshly generated users
+type sandbox struct {
+	store    *userStore
+	size     int
+	interval time.Duration
+	rnd      *rand.Rand
+
+	mu        sync.RWMutex
+	nextReset time.Time
+}
+
+// startSandbox seeds the store with fake users, makes every write to it succeed
+// until it is full and resets it every interval. It must be called before the
+// server starts.
+func startSandbox(store *userStore, size int, interval time.Duration) *sandbox {
+	sb := &sandbox{
+		store:    store,
+		size:     size,
+		interval: interval,
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	store.forgiving = true
+	store.capacity = size + sandboxMaxNewUsers
+	sb.reset()
+
+	go func() {
+		for range time.Tick(interval) {
+			sb.reset()
+			log.Println("sandbox reset")
+		}
+	}()
+
+	return sb
+}
+
+// reset regenerates the content of the store
+func (sb *sandbox) reset() {
+	sb.store.reset(fakeUsers(sb.rnd, sb.size))
+
+	sb.mu.Lock()
+	sb.nextReset = time.Now().Add(sb.interval).UTC()
+	sb.mu.Unlock()
+}
+
+// middleware advertises when the sandbox will next be reset
+func (sb *sandbox) middleware(c *fiber.Ctx) error {
+	sb.mu.RLock()
+	next := sb.nextReset
+	sb.mu.RUnlock()
+
+	c.Set(sandboxResetHeader, next.Format(time.RFC3339))
+	return c.Next()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Errors returned by userStore writes
+var (
+	errUserNotFound = errors.New("user not found")
+	errStoreFull    = errors.New("user store is full")
+)
+
+// userStore is an in-memory, concurrency-safe store of users
+type userStore struct {
+	mu     sync.RWMutex
+	users  map[int]User
+	nextID int
+
+	// forgiving makes writes to missing users succeed: updates create the
+	// user and deletes are no-ops
+	forgiving bool
+	// capacity caps the number of users, 0 means unlimited
+	capacity int
+}
+
+// defaultUserCapacity caps the store outside sandbox mode, so anonymous
+// clients cannot grow the process without bound
+const defaultUserCapacity = 1000
+
+// users backs the user handlers
+var users = newUserStore(defaultUsers())
+
+// defaultUsers returns the users the store starts with
+func defaultUsers() []User {
+	return []User{
+		{ID: 1, Name: "John Doe", Email: "john@example.com", Age: 30},
+		{ID: 2, Name: "Jane Smith", Email: "jane@example.com", Age: 25},
+	}
+}
+
+// newUserStore returns a store holding seed, capped at defaultUserCapacity
+func newUserStore(seed []User) *userStore {
+	s := &userStore{capacity: defaultUserCapacity}
+	s.reset(seed)
+	return s
+}
+
+// reset replaces the content of the store with seed
+func (s *userStore) reset(seed []User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[int]User, len(seed))
+	s.nextID = 1
+	for _, u := range seed {
+		s.users[u.ID] = u
+		if u.ID >= s.nextID {
+			s.nextID = u.ID + 1
+		}
+	}
+}
+
+// list returns every user ordered by ID
+func (s *userStore) list() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out
+}
+
+// find returns the users matching the predicate ordered by ID
+func (s *userStore) find(match userPredicate) []User {
+	all := s.list()
+
+	out := all[:0]
+	for _, u := range all {
+		if match(u) {
+			out = append(out, u)
+		}
+	}
+
+	return out
+}
+
+// get returns the user with the given ID
+func (s *userStore) get(id int) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	return u, ok
+}
+
+// create stores a new user and returns it with its assigned ID
+func (s *userStore) create(req CreateUserRequest) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.full() {
+		return User{}, errStoreFull
+	}
+
+	// IDs above nextID may have been taken by forgiving updates
+	for {
+		if _, taken := s.users[s.nextID]; !taken {
+			break
+		}
+		s.nextID++
+	}
+	u := User{ID: s.nextID, Name: req.Name, Email: req.Email, Age: req.Age}
+	s.users[u.ID] = u
+	s.nextID++
+
+	return u, nil
+}
+
+// update replaces the user with the given ID. Forgiving stores create missing
+// users instead of failing, without moving nextID, so caller-chosen IDs cannot
+// push it towards overflow.
+func (s *userStore) update(id int, req CreateUserRequest) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		if !s.forgiving || id < 1 {
+			return User{}, errUserNotFound
+		}
+		if s.full() {
+			return User{}, errStoreFull
+		}
+	}
+
+	u := User{ID: id, Name: req.Name, Email: req.Email, Age: req.Age}
+	s.users[id] = u
+
+	return u, nil
+}
+
+// full reports whether the store reached its capacity. s.mu must be held.
+func (s *userStore) full() bool {
+	return s.capacity > 0 && len(s.users) >= s.capacity
+}
+
+// delete removes the user with the given ID, reporting whether it succeeded
+func (s *userStore) delete(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok && !s.forgiving {
+		return false
+	}
+	delete(s.users, id)
+
+	return true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUserStoreCapacity(t *testing.T) {
+	s := newUserStore(defaultUsers())
+	if s.capacity != defaultUserCapacity {
+		t.Fatalf("got capacity %d, want %d", s.capacity, defaultUserCapacity)
+	}
+
+	req := CreateUserRequest{Name: "A", Email: "a@example.com", Age: 1}
+	for i := len(defaultUsers()); i < defaultUserCapacity; i++ {
+		if _, err := s.create(req); err != nil {
+			t.Fatalf("create %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.create(req); !errors.Is(err, errStoreFull) {
+		t.Errorf("create past capacity: got %v, want %v", err, errStoreFull)
+	}
+	if _, err := s.update(1, req); err != nil {
+		t.Errorf("updating an existing user in a full store: %v", err)
+	}
+}
+
+func TestForgivingUpdateKeepsNextID(t *testing.T) {
+	s := newUserStore(defaultUsers())
+	s.forgiving = true
+
+	req := CreateUserRequest{Name: "A", Email: "a@example.com", Age: 1}
+	if _, err := s.update(math.MaxInt, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.update(4, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.update(0, req); !errors.Is(err, errUserNotFound) {
+		t.Errorf("update of ID 0: got %v, want %v", err, errUserNotFound)
+	}
+
+	u, err := s.create(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 3 {
+		t.Errorf("first create got ID %d, want 3", u.ID)
+	}
+	if u, _ = s.create(req); u.ID != 5 {
+		t.Errorf("second create got ID %d, want 5, skipping the ID taken by update", u.ID)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/swaggo/swag"
+
+	"fiber-go-swagger/docs"
+)
+
+// swaggerInitScript boots the UI. It is served as a file rather than inlined
+// so the Content-Security-Policy does not need 'unsafe-inline' for scripts.
+const swaggerInitScript = `window.onload = function () {
+  window.ui = SwaggerUIBundle({
+    url: "./doc.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    validatorUrl: null,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout",
+  });
+};
+`
+
+const swaggerIndexTmpl = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8">
+    <title>{{.Title}}</title>
+    <link rel="stylesheet" type="text/css" href="{{.CSS.URL}}" integrity="{{.CSS.Integrity}}">
+    <link rel="stylesheet" type="text/css" href="{{.IndexCSS.URL}}" integrity="{{.IndexCSS.Integrity}}">
+    <link rel="icon" type="image/png" href="{{.Favicon32.URL}}" sizes="32x32">
+    <link rel="icon" type="image/png" href="{{.Favicon16.URL}}" sizes="16x16">
+  </head>
+  <body>
+    <div id="swagger-ui"></div>
+    <script src="{{.Bundle.URL}}" integrity="{{.Bundle.Integrity}}" charset="UTF-8"></script>
+    <script src="{{.Preset.URL}}" integrity="{{.Preset.Integrity}}" charset="UTF-8"></script>
+    <script src="{{.Init.URL}}" integrity="{{.Init.Integrity}}" charset="UTF-8"></script>
+  </body>
+</html>
+`
+
+const (
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	noCacheControl        = "no-cache"
+)
+
+// swaggerAsset is a static file of the documentation UI, fingerprinted so it
+// can be cached forever and pinned with subresource integrity
+type swaggerAsset struct {
+	Name      string
+	Body      []byte
+	Version   string
+	Integrity string
+}
+
+// URL returns the relative, versioned URL of the asset
+func (a *swaggerAsset) URL() string {
+	return "./" + a.Name + "?v=" + a.Version
+}
+
+// newSwaggerAsset fingerprints body
+func newSwaggerAsset(name string, body []byte) *swaggerAsset {
+	version := sha256.Sum256(body)
+	integrity := sha512.Sum384(body)
+
+	return &swaggerAsset{
+		Name:      name,
+		Body:      body,
+		Version:   hex.EncodeToString(version[:8]),
+		Integrity: "sha384-" + base64.StdEncoding.EncodeToString(integrity[:]),
+	}
+}
+
+// swaggerUI returns the handler serving the documentation UI under a wildcard route
+func swaggerUI() fiber.Handler {
+	assets := map[string]*swaggerAsset{}
+	load := func(name string) *swaggerAsset {
+		body, err := fs.ReadFile(swaggerFiles.FS, name)
+		if err != nil {
+			panic("swagger ui: missing asset " + name)
+		}
+		assets[name] = newSwaggerAsset(name, body)
+		return assets[name]
+	}
+
+	page := struct {
+		Title                                                     string
+		CSS, IndexCSS, Favicon32, Favicon16, Bundle, Preset, Init *swaggerAsset
+	}{
+		Title:     docs.SwaggerInfo.Title,
+		CSS:       load("swagger-ui.css"),
+		IndexCSS:  load("index.css"),
+		Favicon32: load("favicon-32x32.png"),
+		Favicon16: load("favicon-16x16.png"),
+		Bundle:    load("swagger-ui-bundle.js"),
+		Preset:    load("swagger-ui-standalone-preset.js"),
+		Init:      newSwaggerAsset("swagger-init.js", []byte(swaggerInitScript)),
+	}
+	assets[page.Init.Name] = page.Init
+
+	var index strings.Builder
+	if err := template.Must(template.New("index").Parse(swaggerIndexTmpl)).Execute(&index, page); err != nil {
+		panic("swagger ui: " + err.Error())
+	}
+
+	csp := swaggerCSP()
+
+	return func(c *fiber.Ctx) error {
+		switch name := c.Params("*"); name {
+		case "", "index.html":
+			if name == "" {
+				return c.Redirect(path.Join(c.Path(), "index.html"), fiber.StatusMovedPermanently)
+			}
+			c.Set(fiber.HeaderContentSecurityPolicy, csp)
+			c.Set(fiber.HeaderCacheControl, noCacheControl)
+			c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+			c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+			c.Type("html")
+			return c.SendString(index.String())
+		case "doc.json":
+			doc, err := swag.ReadDoc()
+			if err != nil {
+				return err
+			}
+			c.Set(fiber.HeaderCacheControl, noCacheControl)
+			c.Type("json")
+			return c.SendString(doc)
+		default:
+			asset, ok := assets[name]
+			if !ok {
+				return fiber.ErrNotFound
+			}
+			// Only the fingerprinted URL may be cached forever; anything else
+			// would pin stale content after an upgrade.
+			if c.Query("v") == asset.Version {
+				c.Set(fiber.HeaderCacheControl, immutableCacheControl)
+			} else {
+				c.Set(fiber.HeaderCacheControl, noCacheControl)
+			}
+			c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+			c.Set(fiber.HeaderContentType, mime.TypeByExtension(path.Ext(name)))
+			return c.Send(asset.Body)
+		}
+	}
+}
+
+// swaggerCSP builds the Content-Security-Policy of the UI page. Requests sent by
+// "try it out" target the host declared in the spec, so it is allowed explicitly
+// alongside the serving origin. Swagger UI sets inline styles, so those are allowed.
+func swaggerCSP() string {
+	connect := []string{"'self'"}
+	if host := docs.SwaggerInfo.Host; host != "" {
+		for _, scheme := range docs.SwaggerInfo.Schemes {
+			connect = append(connect, scheme+"://"+host)
+		}
+	}
+
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self'",
+		"style-src 'self' 'unsafe-inline'",
+		"img-src 'self' data:",
+		"font-src 'self' data:",
+		"connect-src " + strings.Join(connect, " "),
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UsageReport is the rate limit and quota consumption of the caller
+type UsageReport struct {
+	Subject      string           `json:"subject" example:"mobile-app"`
+	RateLimits   []RateLimitUsage `json:"rate_limits"`
+	MonthlyQuota *QuotaUsage      `json:"monthly_quota,omitempty"`
+}
+
+// RateLimitUsage is the caller's consumption of a route's current rate limit window
+type RateLimitUsage struct {
+	Route     string     `json:"route" example:"GET /api/v1/users"`
+	Limit     int        `json:"limit" example:"100"`
+	Used      int        `json:"used" example:"12"`
+	Remaining int        `json:"remaining" example:"88"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"`
+}
+
+// QuotaUsage is the caller's consumption of the monthly quota
+type QuotaUsage struct {
+	Limit     int       `json:"limit" example:"10000"`
+	Used      int       `json:"used" example:"1234"`
+	Remaining int       `json:"remaining" example:"8766"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// getUsage godoc
+// @Summary Get the caller's usage
+// @Description Get the authenticated caller's consumption of every rate limit and of the monthly quota, so clients can throttle themselves
+// @Tags me
+// @Produce json
+// @Success 200 {object} UsageReport
+// @Failure 401 {object} ErrorResponse
+// @Router /me/usage [get]
+func getUsage(cfg Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := currentPrincipal(c)
+		if p == nil {
+			return unauthorized(c, "Missing API key")
+		}
+
+		now := time.Now()
+		caller := callerKey(c)
+
+		report := UsageReport{
+			Subject:    p.Subject,
+			RateLimits: limits.rateLimitUsage(caller, now),
+		}
+		if cfg.MonthlyQuota > 0 {
+			used := limits.quotaUsed(caller, now)
+			report.MonthlyQuota = &QuotaUsage{
+				Limit:     cfg.MonthlyQuota,
+				Used:      used,
+				Remaining: max(0, cfg.MonthlyQuota-used),
+				ResetAt:   monthEnd(now),
+			}
+		}
+
+		return c.JSON(report)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Build information, injected at build time via ldflags:
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// versionHeader is the response header carrying the running version
+const versionHeader = "X-App-Version"
+
+// VersionInfo represents the build information of the running binary
+type VersionInfo struct {
+	Version   string `json:"version" example:"1.2.3"`
+	Commit    string `json:"commit" example:"0f6dbe3"`
+	BuildDate string `json:"build_date" example:"2024-01-01T00:00:00Z"`
+	GoVersion string `json:"go_version" example:"go1.24.0"`
+}
+
+// versionMiddleware adds the running version to every response
+func versionMiddleware(c *fiber.Ctx) error {
+	c.Set(versionHeader, version)
+	return c.Next()
+}
+
+// getVersion returns the build information of the running binary
+func getVersion(c *fiber.Ctx) error {
+	return c.JSON(VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	})
+}